@@ -0,0 +1,242 @@
+package it
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// FromChan returns an iterator that yields values received from ch until it is
+// closed. If the consumer stops early the iterator simply stops receiving; it
+// never closes ch, that remains the responsibility of the sender, which must
+// also make sure it doesn't block forever sending values nobody will receive.
+func FromChan[A any](ch <-chan A) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for a := range ch {
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// FromChanCtx is like FromChan, but also stops as soon as ctx is cancelled.
+// If values are ready to be received and ctx is cancelled at the same time,
+// either may happen first.
+func FromChanCtx[A any](ctx context.Context, ch <-chan A) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case a, ok := <-ch:
+				if !ok {
+					return
+				}
+				if !yield(a) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ToChan starts a goroutine that ranges over it, sending every value on the
+// returned channel, which has a buffer of size buf. The channel is closed when
+// the iterator is exhausted or ctx is cancelled, at which point the goroutine
+// exits. If the caller stops receiving before the channel is closed, it must
+// cancel ctx to avoid leaking the goroutine.
+func ToChan[A any](ctx context.Context, it iter.Seq[A], buf int) <-chan A {
+	ch := make(chan A, buf)
+	go func() {
+		defer close(ch)
+		for a := range it {
+			select {
+			case ch <- a:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// MergeChans returns an iterator that yields values from all of the provided
+// channels as they arrive, finishing once all of them are closed or ctx is
+// cancelled. Each channel is received from by its own goroutine, so a slow
+// channel doesn't hold up the others. There is no ordering between values from
+// different channels.
+//
+// When the iterator finishes, including when the consumer stops early, the
+// internal goroutines are stopped before it returns. Each of them may have
+// received one value that was never yielded, which is discarded.
+func MergeChans[A any](ctx context.Context, chs ...<-chan A) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		defer cancel()
+
+		out := make(chan A)
+		for _, ch := range chs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					var a A
+					select {
+					case <-ctx.Done():
+						return
+					case v, ok := <-ch:
+						if !ok {
+							return
+						}
+						a = v
+					}
+					select {
+					case <-ctx.Done():
+						return
+					case out <- a:
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(out)
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case a, ok := <-out:
+				if !ok {
+					return
+				}
+				if !yield(a) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Prefetch returns an iterator that yields the same values as it, in the same
+// order, but runs it in a separate goroutine so that up to n values can be
+// produced ahead of the consumer. This helps when both producing and consuming
+// each value take a while, for example if the source is doing I/O.
+//
+// If the consumer stops early the goroutine is stopped before the iterator
+// returns; any values that had been produced but not consumed are discarded.
+// If the source panics, the panic is re-raised in the consumer's goroutine
+// once the values produced before it have been yielded. It panics if n is
+// negative.
+func Prefetch[A any](it iter.Seq[A], n int) iter.Seq[A] {
+	if n < 0 {
+		panic(fmt.Sprintf("it.Prefetch: negative buffer size %d", n))
+	}
+	return func(yield func(A) bool) {
+		var (
+			ch       = make(chan A, n)
+			done     = make(chan struct{})
+			finished = make(chan struct{})
+			panicked bool
+			p        any
+		)
+		go func() {
+			defer close(finished)
+			defer close(ch)
+			defer func() {
+				if r := recover(); r != nil {
+					panicked, p = true, r
+				}
+			}()
+			for a := range it {
+				select {
+				case ch <- a:
+				case <-done:
+					return
+				}
+			}
+		}()
+		defer func() {
+			close(done)
+			<-finished
+		}()
+
+		for a := range ch {
+			if !yield(a) {
+				return
+			}
+		}
+		if panicked {
+			panic(p)
+		}
+	}
+}
+
+// FanOut distributes the values from it across n iterators, for consumption by
+// separate goroutines. Each value is yielded by exactly one of the returned
+// iterators, whichever asks for it first, so a slow consumer doesn't hold up
+// the others. Values are passed through a channel with a buffer of size buf.
+//
+// The first time any of the returned iterators is ranged over, a goroutine is
+// started to range over it. It stops when it is exhausted, when ctx is
+// cancelled, or once every one of the returned iterators has been ranged over
+// and has finished, including by the consumer stopping early. Any values
+// buffered at that point are discarded. Each of the returned iterators should
+// only be ranged over once. FanOut panics if n is not positive.
+func FanOut[A any](ctx context.Context, it iter.Seq[A], n, buf int) []iter.Seq[A] {
+	if n <= 0 {
+		panic(fmt.Sprintf("it.FanOut: invalid number of consumers %d", n))
+	}
+	var (
+		ch         = make(chan A, buf)
+		start      sync.Once
+		stopped    atomic.Int32
+		allStopped = make(chan struct{})
+	)
+	produce := func() {
+		go func() {
+			defer close(ch)
+			for a := range it {
+				select {
+				case ch <- a:
+				case <-ctx.Done():
+					return
+				case <-allStopped:
+					return
+				}
+			}
+		}()
+	}
+	seqs := make([]iter.Seq[A], n)
+	for i := range seqs {
+		var stop sync.Once
+		seqs[i] = func(yield func(A) bool) {
+			start.Do(produce)
+			defer stop.Do(func() {
+				if int(stopped.Add(1)) == n {
+					close(allStopped)
+				}
+			})
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case a, ok := <-ch:
+					if !ok {
+						return
+					}
+					if !yield(a) {
+						return
+					}
+				}
+			}
+		}
+	}
+	return seqs
+}
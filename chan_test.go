@@ -0,0 +1,481 @@
+package it
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// produce starts a goroutine that sends values on the returned channel and
+// closes it once they have all been sent, or ctx is cancelled.
+func produce[A any](ctx context.Context, values ...A) <-chan A {
+	ch := make(chan A)
+	go func() {
+		defer close(ch)
+		for _, v := range values {
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func TestFromChan(t *testing.T) {
+	values := []int{1, 2, 3, 4, 5}
+	got := slices.Collect(FromChan(produce(t.Context(), values...)))
+	if d := cmp.Diff(got, values); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestFromChanEarlyBreak(t *testing.T) {
+	ch := make(chan int, 5)
+	for i := range 5 {
+		ch <- i
+	}
+	close(ch)
+	got := slices.Collect(Limit(FromChan(ch), 2))
+	if d := cmp.Diff(got, []int{0, 1}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	// The rest are still there to be received.
+	if d := cmp.Diff(slices.Collect(FromChan(ch)), []int{2, 3, 4}); d != "" {
+		t.Fatalf("remainder mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestFromChanCtx(t *testing.T) {
+	values := []int{1, 2, 3, 4, 5}
+	got := slices.Collect(FromChanCtx(t.Context(), produce(t.Context(), values...)))
+	if d := cmp.Diff(got, values); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestFromChanCtxCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	// A producer that never stops sending.
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := 0; ; i++ {
+			select {
+			case ch <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var got []int
+	for v := range FromChanCtx(ctx, ch) {
+		got = append(got, v)
+		if v == 10 {
+			cancel()
+		}
+	}
+	// Once cancelled, sends and cancellation race, so there might be an
+	// extra value or two but the iterator must finish.
+	if len(got) < 11 {
+		t.Fatalf("stopped too early: %v", got)
+	}
+	if d := cmp.Diff(got[:11], []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestFromChanCtxAlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	// Nothing is ever sent, so this would block forever if the context
+	// wasn't checked.
+	ch := make(chan int)
+	if got := slices.Collect(FromChanCtx(ctx, ch)); got != nil {
+		t.Fatalf("expected nothing, got %v", got)
+	}
+}
+
+// naturals returns an infinite iterator over 0, 1, 2...
+func naturals() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	}
+}
+
+// checkGoroutines returns a function that fails the test if the number of
+// goroutines hasn't returned to what it was when checkGoroutines was called,
+// after giving them some time to finish. Call it as
+//
+//	defer checkGoroutines(t)()
+func checkGoroutines(t *testing.T) func() {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	return func() {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			now := runtime.NumGoroutine()
+			if now <= before {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("leaked goroutines: %d before, %d after", before, now)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestToChan(t *testing.T) {
+	defer checkGoroutines(t)()
+	values := []int{1, 2, 3, 4, 5}
+	for _, buf := range []int{0, 1, 10} {
+		t.Run(strconv.Itoa(buf), func(t *testing.T) {
+			got := slices.Collect(FromChan(ToChan(t.Context(), slices.Values(values), buf)))
+			if d := cmp.Diff(got, values); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestToChanCancel(t *testing.T) {
+	for _, buf := range []int{0, 1, 10} {
+		for _, after := range []int{0, 1, 5, 20} {
+			t.Run(fmt.Sprintf("%d/%d", buf, after), func(t *testing.T) {
+				defer checkGoroutines(t)()
+				ctx, cancel := context.WithCancel(t.Context())
+				ch := ToChan(ctx, naturals(), buf)
+				for range after {
+					<-ch
+				}
+				cancel()
+				// Stop receiving entirely, the goroutine must
+				// still exit.
+			})
+		}
+	}
+}
+
+func TestToChanCancelledIteratorStops(t *testing.T) {
+	defer checkGoroutines(t)()
+	ctx, cancel := context.WithCancel(t.Context())
+	pulled := 0
+	done := make(chan struct{})
+	src := func(yield func(int) bool) {
+		defer close(done)
+		for i := 0; ; i++ {
+			pulled++
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	ch := ToChan(ctx, src, 0)
+	<-ch
+	cancel()
+	<-done
+	// One value received, and one more that was never sent.
+	if pulled != 2 {
+		t.Fatalf("pulled %d elements, want 2", pulled)
+	}
+}
+
+func TestMergeChans(t *testing.T) {
+	defer checkGoroutines(t)()
+	ctx := t.Context()
+	got := slices.Collect(MergeChans(ctx,
+		produce(ctx, 1, 2, 3),
+		produce(ctx, 4, 5),
+		produce[int](ctx),
+		produce(ctx, 6, 7, 8, 9),
+	))
+	slices.Sort(got)
+	if d := cmp.Diff(got, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestMergeChansNone(t *testing.T) {
+	defer checkGoroutines(t)()
+	if got := slices.Collect(MergeChans[int](t.Context())); got != nil {
+		t.Fatalf("expected nothing, got %v", got)
+	}
+}
+
+func TestMergeChansCancel(t *testing.T) {
+	defer checkGoroutines(t)()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	// Neither of these is ever closed.
+	a, b := make(chan int), make(chan int)
+	go func() {
+		a <- 1
+		b <- 2
+	}()
+	var got []int
+	for v := range MergeChans(ctx, a, b) {
+		got = append(got, v)
+		if len(got) == 2 {
+			// The channels are still open, so only this will
+			// stop the iteration.
+			cancel()
+		}
+	}
+	slices.Sort(got)
+	if d := cmp.Diff(got, []int{1, 2}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestMergeChansEarlyBreak(t *testing.T) {
+	defer checkGoroutines(t)()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	fast1 := ToChan(ctx, naturals(), 0)
+	fast2 := ToChan(ctx, naturals(), 0)
+	got := slices.Collect(Limit(MergeChans(t.Context(), fast1, fast2), 10))
+	if len(got) != 10 {
+		t.Fatalf("got %d values, want 10", len(got))
+	}
+	// The fan-in goroutines are gone, the producers will go when ctx is
+	// cancelled by the deferred call.
+}
+
+func TestMergeChansSlowChannel(t *testing.T) {
+	defer checkGoroutines(t)()
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	fast := ToChan(ctx, Map(naturals(), func(int) string { return "fast" }), 0)
+	slow := make(chan string)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		slow <- "slow"
+		close(slow)
+	}()
+	for v := range MergeChans(ctx, fast, slow) {
+		if v == "slow" {
+			return
+		}
+	}
+	t.Fatal("never received from the slow channel")
+}
+
+func TestPrefetch(t *testing.T) {
+	values := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for _, n := range []int{0, 1, 3, 20} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			defer checkGoroutines(t)()
+			got := slices.Collect(Prefetch(slices.Values(values), n))
+			if d := cmp.Diff(got, values); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestPrefetchEarlyBreak(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 20} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			defer checkGoroutines(t)()
+			var (
+				pulled   int
+				returned bool
+			)
+			src := func(yield func(int) bool) {
+				defer func() { returned = true }()
+				for i := 0; ; i++ {
+					pulled++
+					if !yield(i) {
+						return
+					}
+				}
+			}
+			got := slices.Collect(Limit(Prefetch(src, n), 5))
+			if d := cmp.Diff(got, []int{0, 1, 2, 3, 4}); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+			// The source must have finished by the time the
+			// iterator returns, so there's no race reading these.
+			if !returned {
+				t.Fatal("source still running")
+			}
+			// The five values consumed, up to n in the buffer and
+			// one that couldn't be sent.
+			if pulled > 5+n+1 {
+				t.Fatalf("pulled %d values, want at most %d", pulled, 5+n+1)
+			}
+		})
+	}
+}
+
+func TestPrefetchPanic(t *testing.T) {
+	defer checkGoroutines(t)()
+	src := func(yield func(int) bool) {
+		for i := range 3 {
+			if !yield(i) {
+				return
+			}
+		}
+		panic("oh no")
+	}
+	var got []int
+	func() {
+		defer func() {
+			if r := recover(); r != "oh no" {
+				t.Errorf("got panic %v, want %q", r, "oh no")
+			}
+		}()
+		for v := range Prefetch(src, 10) {
+			got = append(got, v)
+		}
+	}()
+	if d := cmp.Diff(got, []int{0, 1, 2}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func BenchmarkPrefetch(b *testing.B) {
+	const (
+		n       = 20
+		latency = 100 * time.Microsecond
+	)
+	slow := func(yield func(int) bool) {
+		for i := range n {
+			time.Sleep(latency)
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	for _, c := range []struct {
+		name string
+		it   iter.Seq[int]
+	}{
+		{name: "none", it: slow},
+		{name: "prefetch-1", it: Prefetch(slow, 1)},
+		{name: "prefetch-10", it: Prefetch(slow, 10)},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			for b.Loop() {
+				for range c.it {
+					time.Sleep(latency)
+				}
+			}
+		})
+	}
+}
+
+// consumeAll ranges over each of the iterators in its own goroutine, stopping
+// after receiving limits[i] values (or never if limits is too short or the
+// limit is not positive), and returns everything they received.
+func consumeAll[A any](its []iter.Seq[A], limits ...int) [][]A {
+	got := make([][]A, len(its))
+	var wg sync.WaitGroup
+	for i, it := range its {
+		limit := -1
+		if i < len(limits) {
+			limit = limits[i]
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range it {
+				got[i] = append(got[i], a)
+				limit--
+				if limit == 0 {
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return got
+}
+
+func TestFanOut(t *testing.T) {
+	for _, n := range []int{1, 2, 5} {
+		for _, buf := range []int{0, 1, 10} {
+			t.Run(fmt.Sprintf("%d/%d", n, buf), func(t *testing.T) {
+				defer checkGoroutines(t)()
+				its := FanOut(t.Context(), Limit(naturals(), 100), n, buf)
+				got := slices.Sorted(Concat(Map(slices.Values(consumeAll(its)), slices.Values)))
+				if d := cmp.Diff(got, slices.Collect(Limit(naturals(), 100))); d != "" {
+					t.Fatalf("mismatch (-got, +want):\n%v", d)
+				}
+			})
+		}
+	}
+}
+
+func TestFanOutPartialConsumption(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		limits []int
+	}{
+		{name: "one-stops-immediately", limits: []int{1, -1, -1}},
+		{name: "one-stops-later", limits: []int{5, -1, -1}},
+		{name: "all-stop", limits: []int{5, 1, 20}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			defer checkGoroutines(t)()
+			const total = 100
+			its := FanOut(t.Context(), Limit(naturals(), total), len(c.limits), 1)
+			got := consumeAll(its, c.limits...)
+			seen := make(map[int]bool)
+			for i, g := range got {
+				if c.limits[i] > 0 && len(g) > c.limits[i] {
+					t.Errorf("consumer %d got %d values, limit %d", i, len(g), c.limits[i])
+				}
+				for _, v := range g {
+					if seen[v] {
+						t.Errorf("%d received twice", v)
+					}
+					seen[v] = true
+				}
+			}
+			// If anyone kept going, nothing should be missing.
+			if slices.Contains(c.limits, -1) && len(seen) != total {
+				t.Errorf("received %d values, want %d", len(seen), total)
+			}
+		})
+	}
+}
+
+func TestFanOutInfiniteSourceAllStop(t *testing.T) {
+	defer checkGoroutines(t)()
+	its := FanOut(t.Context(), naturals(), 3, 4)
+	// Would block forever if the producer didn't notice everyone had
+	// stopped.
+	got := consumeAll(its, 3, 4, 5)
+	for i, g := range got {
+		if len(g) != 3+i {
+			t.Errorf("consumer %d got %d values, want %d", i, len(g), 3+i)
+		}
+	}
+}
+
+func TestFanOutCancel(t *testing.T) {
+	defer checkGoroutines(t)()
+	ctx, cancel := context.WithCancel(t.Context())
+	its := FanOut(ctx, naturals(), 2, 0)
+	// Only one consumer ever ranges, and stops, so only cancellation can
+	// stop the producer.
+	for range its[0] {
+		break
+	}
+	cancel()
+}
@@ -0,0 +1,266 @@
+package it
+
+import (
+	"fmt"
+	"iter"
+	"math/bits"
+)
+
+// CombinationsIndexes returns an iterator over every k-combination of the
+// indices 0..n-1, each as a strictly increasing slice of k indices, in
+// lexicographic order. The same slice is yielded every time, so it must be
+// cloned if it is to be retained, and must not be modified. If k is zero a
+// single empty combination is yielded, and if k is greater than n nothing is.
+// It panics if n or k are negative.
+func CombinationsIndexes(n, k int) iter.Seq[[]int] {
+	if n < 0 || k < 0 {
+		panic(fmt.Sprintf("it.CombinationsIndexes: invalid n=%d, k=%d", n, k))
+	}
+	return func(yield func([]int) bool) {
+		if k > n {
+			return
+		}
+		c := make([]int, k)
+		for i := range c {
+			c[i] = i
+		}
+		for {
+			if !yield(c) {
+				return
+			}
+			// Find the rightmost index that can still move up, move
+			// it, and reset everything after it to follow on.
+			i := k - 1
+			for i >= 0 && c[i] == n-k+i {
+				i--
+			}
+			if i < 0 {
+				return
+			}
+			c[i]++
+			for j := i + 1; j < k; j++ {
+				c[j] = c[j-1] + 1
+			}
+		}
+	}
+}
+
+// CombinationsMinChange is like CombinationsIndexes, but yields the
+// combinations in revolving door order, where each one differs from the one
+// before by swapping one index out for another. Along with each combination it
+// yields a Pair of the index that was removed and the index that was added, or
+// (-1, -1) for the first combination. This allows something computed from the
+// selected indices to be updated for the next combination rather than
+// recomputed. Each combination is still a strictly increasing slice, which is
+// yielded every time, so it must be cloned if it is to be retained, and must
+// not be modified. It panics if n or k are negative.
+func CombinationsMinChange(n, k int) iter.Seq2[[]int, Pair[int, int]] {
+	if n < 0 || k < 0 {
+		panic(fmt.Sprintf("it.CombinationsMinChange: invalid n=%d, k=%d", n, k))
+	}
+	return func(yield func([]int, Pair[int, int]) bool) {
+		if k > n {
+			return
+		}
+		// Algorithm R from Knuth's TAOCP 7.2.1.3. c is one-indexed as in
+		// the book, with c[k+1] = n as a sentinel, so the combination
+		// itself is c[1:k+1].
+		c := make([]int, k+2)
+		for j := 1; j <= k; j++ {
+			c[j] = j - 1
+		}
+		c[k+1] = n
+		if !yield(c[1:k+1], NewPair(-1, -1)) {
+			return
+		}
+		if k == 0 {
+			return
+		}
+		for {
+			removed, added, ok := revolvingDoor(c, k)
+			if !ok || !yield(c[1:k+1], NewPair(removed, added)) {
+				return
+			}
+		}
+	}
+}
+
+// revolvingDoor moves c, as in CombinationsMinChange, on to the next
+// combination, returning the index that was removed and the one that was
+// added, or false if c was the last combination. This is steps R3 to R5 of
+// Algorithm R, and k must be at least 1.
+func revolvingDoor(c []int, k int) (removed, added int, ok bool) {
+	if k%2 == 1 && c[1]+1 < c[2] {
+		c[1]++
+		return c[1] - 1, c[1], true
+	}
+	if k%2 == 0 && c[1] > 0 {
+		c[1]--
+		return c[1] + 1, c[1], true
+	}
+	// Alternately try to decrease and increase c[j], starting with
+	// increasing for even k.
+	decrease := k%2 == 1
+	for j := 2; j <= k; j++ {
+		if decrease {
+			if c[j] >= j {
+				removed, added = c[j], j-2
+				c[j], c[j-1] = c[j-1], j-2
+				return removed, added, true
+			}
+			if j++; j > k {
+				break
+			}
+		}
+		decrease = true
+		if c[j]+1 < c[j+1] {
+			removed, added = j-2, c[j]+1
+			c[j-1], c[j] = c[j], c[j]+1
+			return removed, added, true
+		}
+	}
+	return 0, 0, false
+}
+
+// SetPartitions returns an iterator over every way to split data into
+// non-empty blocks, where neither the order of the blocks nor the order within
+// them matters. Within each block the elements keep their order from data, and
+// the blocks are ordered by their first element. If data is empty, a single
+// partition with no blocks is yielded.
+//
+// The same slices are yielded every time, so they must be cloned if they are
+// to be retained, and must not be modified. Memory use is proportional to
+// len(data), but the number of partitions is the Bell number of len(data),
+// which grows faster than exponentially: there are over a billion partitions
+// of 15 elements and over fifty trillion of 20.
+func SetPartitions[E any, S ~[]E](data S) iter.Seq[[]S] {
+	return func(yield func([]S) bool) {
+		n := len(data)
+		// The partition as a restricted growth string: a[i] is the
+		// block that data[i] is in, and m[i] is the largest block
+		// number in a[:i], so a[i] can be at most m[i]+1.
+		var (
+			a      = make([]int, n)
+			m      = make([]int, n)
+			counts = make([]int, n)
+			buf    = make(S, n)
+			blocks = make([]S, 0, n)
+		)
+		for {
+			// Lay the blocks out one after another in buf.
+			clear(counts)
+			nblocks := 0
+			for _, b := range a {
+				counts[b]++
+				nblocks = max(nblocks, b+1)
+			}
+			blocks = blocks[:0]
+			start := 0
+			for _, c := range counts[:nblocks] {
+				blocks = append(blocks, buf[start:start:start+c])
+				start += c
+			}
+			for i, b := range a {
+				blocks[b] = append(blocks[b], data[i])
+			}
+			if !yield(blocks) {
+				return
+			}
+			// Find the rightmost position that can go up, and reset
+			// everything after it to the first block.
+			i := n - 1
+			for i > 0 && a[i] > m[i] {
+				i--
+			}
+			if i <= 0 {
+				return
+			}
+			a[i]++
+			for j := i + 1; j < n; j++ {
+				a[j] = 0
+				m[j] = max(m[j-1], a[j-1])
+			}
+		}
+	}
+}
+
+// CombinationAt returns the rank'th k-combination of the indices 0..n-1 in
+// lexicographic order, as a strictly increasing slice of k indices. It returns
+// an error if n or k are negative, or if rank is not less than the number of
+// k-combinations of n items.
+//
+// Together with CombinationRank this allows a large space of combinations to
+// be split into ranges and processed independently.
+func CombinationAt(n, k int, rank uint64) ([]int, error) {
+	if n < 0 || k < 0 {
+		return nil, fmt.Errorf("it.CombinationAt: invalid n=%d, k=%d", n, k)
+	}
+	if total, ok := binomial(n, k); ok && rank >= total {
+		return nil, fmt.Errorf("it.CombinationAt: rank %d out of range for n=%d, k=%d", rank, n, k)
+	}
+	ret := make([]int, 0, k)
+	c := 0
+	for i := range k {
+		for ; ; c++ {
+			// The number of combinations that have c in position i,
+			// given the previous positions. If that overflows it is
+			// certainly more than rank.
+			count, ok := binomial(n-1-c, k-1-i)
+			if !ok || rank < count {
+				break
+			}
+			rank -= count
+		}
+		ret = append(ret, c)
+		c++
+	}
+	return ret, nil
+}
+
+// CombinationRank is the inverse of CombinationAt: it returns the position of
+// the provided combination in the lexicographic ordering of all of the
+// len(indices)-combinations of 0..n-1. It returns an error if indices is not
+// strictly increasing, contains values outside [0, n), or if the rank does not
+// fit in a uint64.
+func CombinationRank(indices []int, n int) (uint64, error) {
+	k := len(indices)
+	var rank uint64
+	c := 0
+	for i, idx := range indices {
+		if idx < c || idx >= n {
+			return 0, fmt.Errorf("it.CombinationRank: %v is not a valid combination of %d items", indices, n)
+		}
+		for ; c < idx; c++ {
+			count, ok := binomial(n-1-c, k-1-i)
+			if !ok {
+				return 0, fmt.Errorf("it.CombinationRank: rank of %v overflows uint64", indices)
+			}
+			var carry uint64
+			rank, carry = bits.Add64(rank, count, 0)
+			if carry != 0 {
+				return 0, fmt.Errorf("it.CombinationRank: rank of %v overflows uint64", indices)
+			}
+		}
+		c++
+	}
+	return rank, nil
+}
+
+// binomial returns n choose k, or false if it doesn't fit in a uint64.
+func binomial(n, k int) (uint64, bool) {
+	if k < 0 || k > n {
+		return 0, true
+	}
+	k = min(k, n-k)
+	r := uint64(1)
+	for i := 1; i <= k; i++ {
+		// r * (n-k+i) is always divisible by i, but may need more than
+		// 64 bits before the division.
+		hi, lo := bits.Mul64(r, uint64(n-k+i))
+		if hi >= uint64(i) {
+			return 0, false
+		}
+		r, _ = bits.Div64(hi, lo, uint64(i))
+	}
+	return r, true
+}
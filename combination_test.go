@@ -0,0 +1,308 @@
+package it
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// allCombinations returns every k-combination of 0..n-1 in lexicographic
+// order, the slow way.
+func allCombinations(n, k int) [][]int {
+	var ret [][]int
+	for mask := range 1 << n {
+		var c []int
+		for i := range n {
+			if mask&(1<<i) != 0 {
+				c = append(c, i)
+			}
+		}
+		if len(c) == k {
+			ret = append(ret, append([]int{}, c...))
+		}
+	}
+	slices.SortFunc(ret, slices.Compare)
+	return ret
+}
+
+func TestCombinationsIndexes(t *testing.T) {
+	for n := range 9 {
+		for k := range n + 2 {
+			t.Run(fmt.Sprintf("%d/%d", n, k), func(t *testing.T) {
+				var got [][]int
+				for c := range CombinationsIndexes(n, k) {
+					got = append(got, slices.Clone(c))
+				}
+				want := allCombinations(n, k)
+				if d := cmp.Diff(got, want); d != "" {
+					t.Fatalf("mismatch (-got, +want):\n%v", d)
+				}
+				if count, _ := binomial(n, k); len(got) != int(count) {
+					t.Fatalf("got %d combinations, want %d", len(got), count)
+				}
+			})
+		}
+	}
+}
+
+func TestCombinationsIndexesEarlyBreak(t *testing.T) {
+	var got [][]int
+	for c := range CombinationsIndexes(5, 2) {
+		got = append(got, slices.Clone(c))
+		if len(got) == 3 {
+			break
+		}
+	}
+	if d := cmp.Diff(got, [][]int{{0, 1}, {0, 2}, {0, 3}}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestCombinationsIndexesPanics(t *testing.T) {
+	for _, c := range []struct{ n, k int }{{-1, 0}, {3, -1}, {-2, -2}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("CombinationsIndexes(%d, %d) didn't panic", c.n, c.k)
+				}
+			}()
+			CombinationsIndexes(c.n, c.k)
+		}()
+	}
+}
+
+func TestCombinationsMinChange(t *testing.T) {
+	for n := range 9 {
+		for k := range n + 2 {
+			t.Run(fmt.Sprintf("%d/%d", n, k), func(t *testing.T) {
+				var got [][]int
+				for c, change := range CombinationsMinChange(n, k) {
+					if !slices.IsSorted(c) || len(c) != k {
+						t.Fatalf("invalid combination %v", c)
+					}
+					if got == nil {
+						if change != NewPair(-1, -1) {
+							t.Fatalf("first change = %v, want (-1, -1)", change)
+						}
+						got = append(got, slices.Clone(c))
+						continue
+					}
+					// Undoing the change gives the previous
+					// combination.
+					prev := got[len(got)-1]
+					removed, added := change.Values()
+					if !slices.Contains(c, added) || slices.Contains(c, removed) {
+						t.Fatalf("%v doesn't follow %v with change %v", c, prev, change)
+					}
+					undo := slices.Clone(c)
+					undo[slices.Index(undo, added)] = removed
+					slices.Sort(undo)
+					if !slices.Equal(undo, prev) {
+						t.Fatalf("%v doesn't follow %v with change %v", c, prev, change)
+					}
+					got = append(got, slices.Clone(c))
+				}
+				// Every combination exactly once.
+				slices.SortFunc(got, slices.Compare)
+				if d := cmp.Diff(got, allCombinations(n, k)); d != "" {
+					t.Fatalf("mismatch (-got, +want):\n%v", d)
+				}
+			})
+		}
+	}
+}
+
+func TestCombinationsMinChangeExplicit(t *testing.T) {
+	var got []string
+	for c, change := range CombinationsMinChange(4, 2) {
+		got = append(got, fmt.Sprint(c, change))
+	}
+	want := []string{
+		"[0 1] (-1, -1)",
+		"[1 2] (0, 2)",
+		"[0 2] (1, 0)",
+		"[2 3] (0, 3)",
+		"[1 3] (2, 1)",
+		"[0 3] (1, 0)",
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestCombinationsMinChangePanics(t *testing.T) {
+	for _, c := range []struct{ n, k int }{{-1, 0}, {3, -1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("CombinationsMinChange(%d, %d) didn't panic", c.n, c.k)
+				}
+			}()
+			CombinationsMinChange(c.n, c.k)
+		}()
+	}
+}
+
+func TestSetPartitions(t *testing.T) {
+	bell := []int{1, 1, 2, 5, 15, 52, 203, 877, 4140}
+	for n, want := range bell {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			data := make([]int, n)
+			for i := range data {
+				data[i] = i
+			}
+			seen := make(map[string]bool)
+			for p := range SetPartitions(data) {
+				s := fmt.Sprint(p)
+				if seen[s] {
+					t.Fatalf("partition seen twice: %v", s)
+				}
+				seen[s] = true
+				var all []int
+				for i, b := range p {
+					if len(b) == 0 {
+						t.Fatalf("empty block in %v", p)
+					}
+					if !slices.IsSorted(b) {
+						t.Fatalf("block out of order in %v", p)
+					}
+					if i > 0 && p[i-1][0] > b[0] {
+						t.Fatalf("blocks out of order in %v", p)
+					}
+					all = append(all, b...)
+				}
+				slices.Sort(all)
+				if !slices.Equal(all, data) {
+					t.Fatalf("%v is not a partition of %v", p, data)
+				}
+			}
+			if len(seen) != want {
+				t.Fatalf("got %d partitions, want %d", len(seen), want)
+			}
+		})
+	}
+}
+
+func TestSetPartitionsExplicit(t *testing.T) {
+	var got [][]string
+	for p := range SetPartitions([]string{"a", "b", "c"}) {
+		got = append(got, slices.Collect(Map(slices.Values(p), func(b []string) string {
+			return strings.Join(b, "")
+		})))
+	}
+	want := [][]string{
+		{"abc"},
+		{"ab", "c"},
+		{"ac", "b"},
+		{"a", "bc"},
+		{"a", "b", "c"},
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestCombinationAtLexicographic(t *testing.T) {
+	for n := range 8 {
+		for k := range n + 2 {
+			t.Run(fmt.Sprintf("%d/%d", n, k), func(t *testing.T) {
+				want := allCombinations(n, k)
+				var got [][]int
+				for rank := uint64(0); ; rank++ {
+					c, err := CombinationAt(n, k, rank)
+					if err != nil {
+						break
+					}
+					got = append(got, c)
+				}
+				if d := cmp.Diff(got, want); d != "" {
+					t.Fatalf("mismatch (-got, +want):\n%v", d)
+				}
+				for rank, c := range want {
+					got, err := CombinationRank(c, n)
+					if err != nil {
+						t.Fatalf("CombinationRank(%v, %d): %v", c, n, err)
+					}
+					if got != uint64(rank) {
+						t.Fatalf("CombinationRank(%v, %d) = %d, want %d", c, n, got, rank)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCombinationRankRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for _, c := range []struct{ n, k int }{
+		{10, 3}, {50, 25}, {67, 33}, {1000, 4}, {1000, 996},
+	} {
+		total, ok := binomial(c.n, c.k)
+		if !ok {
+			t.Fatalf("binomial(%d, %d) overflowed", c.n, c.k)
+		}
+		for range 100 {
+			rank := r.Uint64N(total)
+			t.Run(fmt.Sprintf("%d/%d/%d", c.n, c.k, rank), func(t *testing.T) {
+				indices, err := CombinationAt(c.n, c.k, rank)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := CombinationRank(indices, c.n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != rank {
+					t.Fatalf("CombinationRank(CombinationAt(%d)) = %d", rank, got)
+				}
+			})
+		}
+	}
+}
+
+func TestCombinationOverflow(t *testing.T) {
+	// C(100, 50) is much bigger than a uint64, so the first few ranks are
+	// still fine but the last combination can't be ranked.
+	if _, err := CombinationAt(100, 50, 12345); err != nil {
+		t.Fatalf("CombinationAt: unexpected error: %v", err)
+	}
+	last := make([]int, 50)
+	for i := range last {
+		last[i] = 50 + i
+	}
+	if _, err := CombinationRank(last, 100); err == nil {
+		t.Fatal("CombinationRank: expected overflow error")
+	}
+}
+
+func TestCombinationErrors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		f    func() error
+	}{{
+		name: "at-negative-n",
+		f:    func() error { _, err := CombinationAt(-1, 0, 0); return err },
+	}, {
+		name: "at-k-greater-than-n",
+		f:    func() error { _, err := CombinationAt(3, 4, 0); return err },
+	}, {
+		name: "at-out-of-range",
+		f:    func() error { _, err := CombinationAt(5, 2, 10); return err },
+	}, {
+		name: "rank-not-increasing",
+		f:    func() error { _, err := CombinationRank([]int{1, 1}, 5); return err },
+	}, {
+		name: "rank-out-of-range",
+		f:    func() error { _, err := CombinationRank([]int{1, 5}, 5); return err },
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			if c.f() == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
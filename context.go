@@ -0,0 +1,60 @@
+package it
+
+import (
+	"context"
+	"iter"
+)
+
+// WithContext returns an iterator that yields the values from it until ctx is
+// cancelled. The context is checked before every value is yielded, so no
+// values are yielded after cancellation, but the iterator won't notice while
+// it is waiting for the next value from it.
+func WithContext[A any](ctx context.Context, it iter.Seq[A]) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		if ctx.Err() != nil {
+			return
+		}
+		for a := range it {
+			if ctx.Err() != nil || !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// WithContext2 is WithContext for an iter.Seq2.
+func WithContext2[A, B any](ctx context.Context, it iter.Seq2[A, B]) iter.Seq2[A, B] {
+	return func(yield func(A, B) bool) {
+		if ctx.Err() != nil {
+			return
+		}
+		for a, b := range it {
+			if ctx.Err() != nil || !yield(a, b) {
+				return
+			}
+		}
+	}
+}
+
+// WithContextErr is like WithContext, but if ctx is cancelled it yields the
+// zero value and ctx.Err() as a final element, so that callers can tell
+// whether the iterator finished or was cancelled with, for example,
+// CollectErr.
+func WithContextErr[A any](ctx context.Context, it iter.Seq[A]) iter.Seq2[A, error] {
+	return func(yield func(A, error) bool) {
+		var zero A
+		if err := ctx.Err(); err != nil {
+			yield(zero, err)
+			return
+		}
+		for a := range it {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(a, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package it
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithContext(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		cancelAt int // -1 for before starting
+		want     []int
+		wantErr  error
+	}{{
+		name:     "before",
+		cancelAt: -1,
+		want:     nil,
+		wantErr:  context.Canceled,
+	}, {
+		name:     "during",
+		cancelAt: 3,
+		want:     []int{0, 1, 2, 3},
+		wantErr:  context.Canceled,
+	}, {
+		name:     "after",
+		cancelAt: 100,
+		want:     []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			// Cancels the context when the source gets to cancelAt,
+			// after yielding it.
+			source := func(cancel func()) iter.Seq[int] {
+				return func(yield func(int) bool) {
+					for i := range 10 {
+						if !yield(i) {
+							return
+						}
+						if i == c.cancelAt {
+							cancel()
+						}
+					}
+				}
+			}
+			newCtx := func() (context.Context, func()) {
+				ctx, cancel := context.WithCancel(t.Context())
+				if c.cancelAt < 0 {
+					cancel()
+				}
+				return ctx, cancel
+			}
+
+			ctx, cancel := newCtx()
+			got := slices.Collect(WithContext(ctx, source(cancel)))
+			cancel()
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("WithContext mismatch (-got, +want):\n%v", d)
+			}
+
+			ctx, cancel = newCtx()
+			second := func(_, v int) int { return v }
+			got = slices.Collect(Map2x1(WithContext2(ctx, Enumerate(source(cancel))), second))
+			cancel()
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("WithContext2 mismatch (-got, +want):\n%v", d)
+			}
+
+			ctx, cancel = newCtx()
+			got, err := CollectErr(WithContextErr(ctx, source(cancel)))
+			cancel()
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("WithContextErr mismatch (-got, +want):\n%v", d)
+			}
+			if err != c.wantErr {
+				t.Errorf("WithContextErr: got error %v, want %v", err, c.wantErr)
+			}
+		})
+	}
+}
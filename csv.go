@@ -0,0 +1,77 @@
+package it
+
+import (
+	"encoding/csv"
+	"io"
+	"iter"
+)
+
+// CSVRecords returns an iterator over the records read from r. Any error other
+// than io.EOF, including a record with the wrong number of fields, is yielded
+// as the final element. Set r.FieldsPerRecord to -1 to allow ragged rows. If
+// r.ReuseRecord is set the yielded slices are only valid until the next
+// record is yielded.
+func CSVRecords(r *csv.Reader) iter.Seq2[[]string, error] {
+	return func(yield func([]string, error) bool) {
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CSVInto is like CSVRecords, but turns each record into a T using bind. An
+// error from bind is yielded as the final element, just like an error from
+// r.
+func CSVInto[T any](r *csv.Reader, bind func([]string) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		for record, err := range CSVRecords(r) {
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			t, err := bind(record)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CSVWrite writes every record from it to w and then flushes it. It stops at
+// and returns the first error from writing or flushing.
+func CSVWrite(w *csv.Writer, it iter.Seq[[]string]) error {
+	for record := range it {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// CSVWriteFunc is like CSVWrite, but turns each value of it into a record using
+// f.
+func CSVWriteFunc[T any](w *csv.Writer, it iter.Seq[T], f func(T) []string) error {
+	for t := range it {
+		if err := w.Write(f(t)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,191 @@
+package it
+
+import (
+	"encoding/csv"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCSVRecords(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		in      string
+		fields  int
+		want    [][]string
+		wantErr error
+	}{{
+		name: "empty",
+		in:   "",
+	}, {
+		name: "simple",
+		in:   "a,b\nc,d\n",
+		want: [][]string{{"a", "b"}, {"c", "d"}},
+	}, {
+		name: "quoted",
+		in:   "\"a,b\",\"c\"\"d\"\n\"e\nf\",g",
+		want: [][]string{{"a,b", `c"d`}, {"e\nf", "g"}},
+	}, {
+		name:    "ragged",
+		in:      "a,b\nc,d\ne\nf,g\n",
+		want:    [][]string{{"a", "b"}, {"c", "d"}},
+		wantErr: csv.ErrFieldCount,
+	}, {
+		name:   "ragged-allowed",
+		in:     "a,b\nc,d\ne\nf,g\n",
+		fields: -1,
+		want:   [][]string{{"a", "b"}, {"c", "d"}, {"e"}, {"f", "g"}},
+	}, {
+		name:    "bad-quote",
+		in:      "a,b\nc,\"d\n",
+		want:    [][]string{{"a", "b"}},
+		wantErr: csv.ErrQuote,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			r := csv.NewReader(strings.NewReader(c.in))
+			r.FieldsPerRecord = c.fields
+			got, err := CollectErr(CSVRecords(r))
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if !errors.Is(err, c.wantErr) {
+				t.Errorf("got error %v, want %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestCSVRecordsEarlyBreak(t *testing.T) {
+	r := csv.NewReader(strings.NewReader("a\nb\nc\nd\n"))
+	var got [][]string
+	for record, err := range CSVRecords(r) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, record)
+		if len(got) == 2 {
+			break
+		}
+	}
+	if d := cmp.Diff(got, [][]string{{"a"}, {"b"}}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	// Nothing more should have been read.
+	if record, err := r.Read(); err != nil || record[0] != "c" {
+		t.Errorf("Read() after break = %q, %v, want [c], nil", record, err)
+	}
+}
+
+type row struct {
+	Name  string
+	Count int
+}
+
+func bindRow(record []string) (row, error) {
+	n, err := strconv.Atoi(record[1])
+	return row{Name: record[0], Count: n}, err
+}
+
+func TestCSVInto(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		in      string
+		want    []row
+		wantErr error
+	}{{
+		name: "ok",
+		in:   "a,1\nb,2\n\"c,d\",3\n",
+		want: []row{{"a", 1}, {"b", 2}, {"c,d", 3}},
+	}, {
+		name:    "bind-error",
+		in:      "a,1\nb,two\nc,3\n",
+		want:    []row{{"a", 1}},
+		wantErr: strconv.ErrSyntax,
+	}, {
+		name:    "parse-error",
+		in:      "a,1\nb\nc,3\n",
+		want:    []row{{"a", 1}},
+		wantErr: csv.ErrFieldCount,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := CollectErr(CSVInto(csv.NewReader(strings.NewReader(c.in)), bindRow))
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if !errors.Is(err, c.wantErr) {
+				t.Errorf("got error %v, want %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestCSVWrite(t *testing.T) {
+	rows := []row{{"a", 1}, {"b,c", 2}, {`d"e`, 3}}
+	records := [][]string{{"a", "1"}, {"b,c", "2"}, {`d"e`, "3"}}
+	want := "a,1\n\"b,c\",2\n\"d\"\"e\",3\n"
+	for _, c := range []struct {
+		name  string
+		write func(*csv.Writer) error
+	}{{
+		name:  "records",
+		write: func(w *csv.Writer) error { return CSVWrite(w, slices.Values(records)) },
+	}, {
+		name: "func",
+		write: func(w *csv.Writer) error {
+			return CSVWriteFunc(w, slices.Values(rows), func(r row) []string {
+				return []string{r.Name, strconv.Itoa(r.Count)}
+			})
+		},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			var buf strings.Builder
+			if err := c.write(csv.NewWriter(&buf)); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != want {
+				t.Errorf("wrote %q, want %q", got, want)
+			}
+			got, err := CollectErr(CSVInto(csv.NewReader(strings.NewReader(buf.String())), bindRow))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(got, rows); d != "" {
+				t.Errorf("round trip mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestCSVWriteError(t *testing.T) {
+	long := strings.Repeat("x", 1000)
+	records := make([][]string, 100)
+	for i := range records {
+		records[i] = []string{strconv.Itoa(i), long}
+	}
+	for _, c := range []struct {
+		name      string
+		records   [][]string
+		n         int
+		maxPulled int
+	}{
+		// Fails as soon as csv.Writer's buffer fills up.
+		{name: "mid-stream", records: records, n: 2000, maxPulled: 10},
+		// Everything fits in the buffer, so only the final flush fails.
+		{name: "flush", records: records[:2], n: 0, maxPulled: 2},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			seq, pulled, _ := countingSeq(c.records...)
+			err := CSVWrite(csv.NewWriter(&brokenWriter{n: c.n}), seq)
+			if !errors.Is(err, errWrite) {
+				t.Errorf("got error %v, want %v", err, errWrite)
+			}
+			if *pulled > c.maxPulled {
+				t.Errorf("pulled %d records, want at most %d", *pulled, c.maxPulled)
+			}
+		})
+	}
+}
@@ -1,6 +1,12 @@
 package it
 
-import "iter"
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"time"
+)
 
 // CollectErr collects all of the A elements from the iterator, up until the
 // first non-nil error. When a non-nil error is encountered it is immediately
@@ -19,3 +25,318 @@ func CollectErr[A any](i iter.Seq2[A, error]) ([]A, error) {
 	return values, nil
 }
 
+// CollectAllErr is like CollectErr, but rather than stopping at the first
+// error it keeps going, collecting every value paired with a nil error and
+// returning all of the errors joined together with errors.Join, in the order
+// they were encountered. The returned error is nil if there were no errors.
+func CollectAllErr[A any](i iter.Seq2[A, error]) ([]A, error) {
+	var (
+		values []A
+		errs   []error
+	)
+	for a, err := range i {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		values = append(values, a)
+	}
+	return values, errors.Join(errs...)
+}
+
+// CollectErrWith is like CollectErr, but for iterators that may yield a value
+// alongside an error, such as a partially decoded record. It returns the values
+// collected before the first non-nil error, the value that was yielded with
+// that error, and the error itself. If there is no error, the second result is
+// the zero value.
+func CollectErrWith[A any](i iter.Seq2[A, error]) ([]A, A, error) {
+	var values []A
+	for a, err := range i {
+		if err != nil {
+			return values, a, err
+		}
+		values = append(values, a)
+	}
+	var zero A
+	return values, zero, nil
+}
+
+// MapErr applies a fallible function to every item in the iterator, yielding
+// each result along with its error. The result can be passed straight to
+// CollectErr.
+func MapErr[A, B any](as iter.Seq[A], f func(A) (B, error)) iter.Seq2[B, error] {
+	return Map1x2(as, f)
+}
+
+// Map2Err is like MapErr, but for an input that is already fallible. Elements
+// with a non-nil error are passed through as the zero value and the error
+// without calling f.
+func Map2Err[A, B any](as iter.Seq2[A, error], f func(A) (B, error)) iter.Seq2[B, error] {
+	return func(yield func(B, error) bool) {
+		for a, err := range as {
+			if err != nil {
+				var zero B
+				if !yield(zero, err) {
+					return
+				}
+				continue
+			}
+			if !yield(f(a)) {
+				return
+			}
+		}
+	}
+}
+
+// FilterErr is like Filter, but for fallible iterators. Values with a nil error
+// are yielded only if p returns true, elements with a non-nil error are always
+// passed through without calling p.
+func FilterErr[A any](it iter.Seq2[A, error], p func(A) bool) iter.Seq2[A, error] {
+	return func(yield func(A, error) bool) {
+		for a, err := range it {
+			if err == nil && !p(a) {
+				continue
+			}
+			if !yield(a, err) {
+				return
+			}
+		}
+	}
+}
+
+// Must converts a fallible iterator into an infallible one by panicking on the
+// first non-nil error. The panic value is an error wrapping the original, so it
+// can be inspected with errors.Is or errors.As after a recover.
+//
+// This is only intended for tests and tools where any error is fatal anyway,
+// it should not be used to handle errors in real code.
+func Must[A any](it iter.Seq2[A, error]) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for a, err := range it {
+			if err != nil {
+				panic(fmt.Errorf("it.Must: %w", err))
+			}
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// Must1 returns a, or panics with an error wrapping err if it is non-nil. It is
+// useful for calling functions that return an iterator and an error, such as
+// Must1(f()).
+//
+// Like Must, this is only intended for tests and tools.
+func Must1[A any](a A, err error) A {
+	if err != nil {
+		panic(fmt.Errorf("it.Must1: %w", err))
+	}
+	return a
+}
+
+// PartitionErr collects all of the elements of a fallible iterator in a single
+// pass, returning the values paired with a nil error and the non-nil errors
+// separately, each in the order they were encountered.
+func PartitionErr[A any](it iter.Seq2[A, error]) ([]A, []error) {
+	var (
+		values []A
+		errs   []error
+	)
+	for a, err := range it {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		values = append(values, a)
+	}
+	return values, errs
+}
+
+// WrapErr returns an iterator that passes values through unchanged, but
+// replaces every non-nil error with the result of calling wrap on it. wrap is
+// never called with a nil error.
+func WrapErr[A any](it iter.Seq2[A, error], wrap func(error) error) iter.Seq2[A, error] {
+	return func(yield func(A, error) bool) {
+		for a, err := range it {
+			if err != nil {
+				err = wrap(err)
+			}
+			if !yield(a, err) {
+				return
+			}
+		}
+	}
+}
+
+// WrapErrf is a convenience for WrapErr that wraps errors using fmt.Errorf
+// with the provided format and arguments, followed by ": " and the original
+// error, which is wrapped with %w.
+func WrapErrf[A any](it iter.Seq2[A, error], format string, args ...any) iter.Seq2[A, error] {
+	return WrapErr(it, func(err error) error {
+		return fmt.Errorf(format+": %w", append(slices.Clip(args), err)...)
+	})
+}
+
+// FirstErr ranges over the iterator, discarding values, until it finds a
+// non-nil error, which it returns immediately without pulling anything further
+// from the iterator. If there are no errors it returns nil. This is useful for
+// iterators that are only run for their side effects.
+func FirstErr[A any](it iter.Seq2[A, error]) error {
+	for _, err := range it {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnError returns an iterator that passes every element through unchanged, but
+// calls f with each non-nil error just before it is yielded. f is only called
+// for elements that are actually requested by the consumer.
+func OnError[A any](it iter.Seq2[A, error], f func(error)) iter.Seq2[A, error] {
+	return func(yield func(A, error) bool) {
+		for a, err := range it {
+			if err != nil {
+				f(err)
+			}
+			if !yield(a, err) {
+				return
+			}
+		}
+	}
+}
+
+// Retry returns an iterator over the values produced by repeated calls to f.
+// f returns the next value, whether there was one (false means the sequence is
+// finished) and an error. If f returns a non-nil error it is called again, up
+// to a total of attempts times for the same element, sleeping for
+// backoff(retry) before each retry, where retry counts from 1. A nil backoff
+// retries immediately. If every attempt fails, the last error is yielded and
+// iteration continues with the next element. It panics if attempts is less
+// than 1.
+func Retry[A any](f func() (A, bool, error), attempts int, backoff func(retry int) time.Duration) iter.Seq2[A, error] {
+	if attempts < 1 {
+		panic(fmt.Sprintf("it.Retry: attempts must be at least 1, got %d", attempts))
+	}
+	return func(yield func(A, error) bool) {
+		for {
+			var (
+				a   A
+				ok  bool
+				err error
+			)
+			for retry := range attempts {
+				if retry > 0 && backoff != nil {
+					time.Sleep(backoff(retry))
+				}
+				a, ok, err = f()
+				if err == nil {
+					break
+				}
+			}
+			if err == nil && !ok {
+				return
+			}
+			if !yield(a, err) {
+				return
+			}
+		}
+	}
+}
+
+// ValuesOK returns an iterator over just the values from it that were paired
+// with a nil error. Elements with a non-nil error are silently dropped.
+func ValuesOK[A any](it iter.Seq2[A, error]) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for a, err := range it {
+			if err != nil {
+				continue
+			}
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// Errs returns an iterator over just the non-nil errors from it.
+func Errs[A any](it iter.Seq2[A, error]) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		for _, err := range it {
+			if err == nil {
+				continue
+			}
+			if !yield(err) {
+				return
+			}
+		}
+	}
+}
+
+// StopOnErr returns an iterator that yields the elements of it unchanged, but
+// finishes immediately after yielding the first non-nil error, without pulling
+// anything further from it. It is the lazy equivalent of CollectErr.
+func StopOnErr[A any](it iter.Seq2[A, error]) iter.Seq2[A, error] {
+	return func(yield func(A, error) bool) {
+		for a, err := range it {
+			if !yield(a, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// LiftErr converts an infallible iterator into a fallible one, pairing every
+// element with a nil error, so that it can be used with functions like
+// FilterErr or CollectErr.
+func LiftErr[A any](it iter.Seq[A]) iter.Seq2[A, error] {
+	return func(yield func(A, error) bool) {
+		for a := range it {
+			if !yield(a, nil) {
+				return
+			}
+		}
+	}
+}
+
+// DropNilErr is the reverse of LiftErr: it yields the values from a fallible
+// iterator that is expected never to produce an error. If it does produce a
+// non-nil error, DropNilErr panics with an error wrapping it. Like Must, that
+// makes it unsuitable for handling errors that can actually happen.
+func DropNilErr[A any](it iter.Seq2[A, error]) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for a, err := range it {
+			if err != nil {
+				panic(fmt.Errorf("it.DropNilErr: unexpected error: %w", err))
+			}
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// CollectErrN is like CollectErr, but stops after collecting n values,
+// returning a nil error, if that happens before an error is encountered.
+// Nothing further is pulled from the iterator after the nth value. A negative n
+// means no limit, in which case it is equivalent to CollectErr.
+func CollectErrN[A any](i iter.Seq2[A, error], n int) ([]A, error) {
+	if n < 0 {
+		return CollectErr(i)
+	}
+	var values []A
+	if n == 0 {
+		return values, nil
+	}
+	for a, err := range i {
+		if err != nil {
+			return values, err
+		}
+		values = append(values, a)
+		if len(values) == n {
+			break
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,611 @@
+package it
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// valueOrErr is an element of a fallible sequence for use in test tables.
+type valueOrErr[A any] struct {
+	v   A
+	err error
+}
+
+func fallible[A any](vs ...valueOrErr[A]) iter.Seq2[A, error] {
+	return func(yield func(A, error) bool) {
+		for _, v := range vs {
+			if !yield(v.v, v.err) {
+				return
+			}
+		}
+	}
+}
+
+func val[A any](a A) valueOrErr[A] { return valueOrErr[A]{v: a} }
+
+func fail[A any](err error) valueOrErr[A] { return valueOrErr[A]{err: err} }
+
+var (
+	errA = errors.New("a")
+	errB = errors.New("b")
+	errC = errors.New("c")
+)
+
+func TestCollectAllErr(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		in       []valueOrErr[int]
+		want     []int
+		wantErrs []error
+	}{{
+		name: "no-errors",
+		in:   []valueOrErr[int]{val(1), val(2), val(3)},
+		want: []int{1, 2, 3},
+	}, {
+		name:     "start",
+		in:       []valueOrErr[int]{fail[int](errA), val(1), val(2)},
+		want:     []int{1, 2},
+		wantErrs: []error{errA},
+	}, {
+		name:     "middle",
+		in:       []valueOrErr[int]{val(1), fail[int](errA), fail[int](errB), val(2)},
+		want:     []int{1, 2},
+		wantErrs: []error{errA, errB},
+	}, {
+		name:     "end",
+		in:       []valueOrErr[int]{val(1), val(2), fail[int](errA)},
+		want:     []int{1, 2},
+		wantErrs: []error{errA},
+	}, {
+		name:     "everywhere",
+		in:       []valueOrErr[int]{fail[int](errA), val(1), fail[int](errB), val(2), fail[int](errC)},
+		want:     []int{1, 2},
+		wantErrs: []error{errA, errB, errC},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := CollectAllErr(fallible(c.in...))
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if c.wantErrs == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			joined, ok := err.(interface{ Unwrap() []error })
+			if !ok {
+				t.Fatalf("expected joined error, got %v", err)
+			}
+			if !slices.Equal(joined.Unwrap(), c.wantErrs) {
+				t.Fatalf("got errors %v, want %v", joined.Unwrap(), c.wantErrs)
+			}
+		})
+	}
+}
+
+func TestCollectErrWith(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		in        []valueOrErr[string]
+		want      []string
+		wantValue string
+		wantErr   error
+	}{{
+		name: "no-errors",
+		in:   []valueOrErr[string]{val("a"), val("b")},
+		want: []string{"a", "b"},
+	}, {
+		name:      "first",
+		in:        []valueOrErr[string]{{"partial", errA}, val("b")},
+		wantValue: "partial",
+		wantErr:   errA,
+	}, {
+		name:      "later",
+		in:        []valueOrErr[string]{val("a"), {"partial", errA}, {"ignored", errB}},
+		want:      []string{"a"},
+		wantValue: "partial",
+		wantErr:   errA,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got, v, err := CollectErrWith(fallible(c.in...))
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if v != c.wantValue {
+				t.Errorf("got value %q alongside error, want %q", v, c.wantValue)
+			}
+			if err != c.wantErr {
+				t.Errorf("got error %v, want %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapErr(t *testing.T) {
+	got, err := CollectErr(MapErr(slices.Values([]string{"1", "2", "x", "4"}), strconv.Atoi))
+	if d := cmp.Diff(got, []int{1, 2}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("got error %v, want %v", err, strconv.ErrSyntax)
+	}
+}
+
+func TestMap2Err(t *testing.T) {
+	var calls []string
+	atoi := func(s string) (int, error) {
+		calls = append(calls, s)
+		return strconv.Atoi(s)
+	}
+	in := fallible(val("1"), fail[string](errA), val("x"), val("3"))
+
+	var got []valueOrErr[int]
+	for v, err := range Map2Err(in, atoi) {
+		if err != nil {
+			// Only keep the sentinel errors, the ones from strconv
+			// are hard to compare.
+			if !errors.Is(err, errA) {
+				err = errB
+			}
+		}
+		got = append(got, valueOrErr[int]{v, err})
+	}
+	want := []valueOrErr[int]{val(1), fail[int](errA), fail[int](errB), val(3)}
+	if d := cmp.Diff(got, want, cmp.AllowUnexported(valueOrErr[int]{}), cmpopts.EquateErrors()); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(calls, []string{"1", "x", "3"}); d != "" {
+		t.Errorf("f called with unexpected values (-got, +want):\n%v", d)
+	}
+}
+
+func TestFilterErr(t *testing.T) {
+	in := fallible(val(1), fail[int](errA), val(2), val(3), fail[int](errB), val(5), val(6))
+	var seen []int
+	even := func(i int) bool {
+		seen = append(seen, i)
+		return i%2 == 0
+	}
+
+	var got []valueOrErr[int]
+	for v, err := range FilterErr(in, even) {
+		got = append(got, valueOrErr[int]{v, err})
+	}
+	want := []valueOrErr[int]{fail[int](errA), val(2), fail[int](errB), val(6)}
+	if d := cmp.Diff(got, want, cmp.AllowUnexported(valueOrErr[int]{}), cmpopts.EquateErrors()); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(seen, []int{1, 2, 3, 5, 6}); d != "" {
+		t.Errorf("p called with unexpected values (-got, +want):\n%v", d)
+	}
+}
+
+// recoverErr calls f and returns the error it panicked with, or nil if it
+// didn't panic.
+func recoverErr(t *testing.T, f func()) (err error) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		var ok bool
+		if err, ok = r.(error); !ok {
+			t.Fatalf("panicked with non-error %v", r)
+		}
+	}()
+	f()
+	return nil
+}
+
+func TestMust(t *testing.T) {
+	var got []int
+	err := recoverErr(t, func() {
+		for v := range Must(fallible(val(1), val(2), fail[int](errA), val(3))) {
+			got = append(got, v)
+		}
+	})
+	if !errors.Is(err, errA) {
+		t.Errorf("got panic %v, want one wrapping %v", err, errA)
+	}
+	if d := cmp.Diff(got, []int{1, 2}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+
+	err = recoverErr(t, func() {
+		got = slices.Collect(Must(fallible(val(1), val(2))))
+	})
+	if err != nil {
+		t.Errorf("unexpected panic: %v", err)
+	}
+	if d := cmp.Diff(got, []int{1, 2}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestMust1(t *testing.T) {
+	if got := Must1(1, nil); got != 1 {
+		t.Errorf("Must1(1, nil) = %d", got)
+	}
+	err := recoverErr(t, func() { Must1(1, errA) })
+	if !errors.Is(err, errA) {
+		t.Errorf("got panic %v, want one wrapping %v", err, errA)
+	}
+}
+
+func TestPartitionErr(t *testing.T) {
+	values, errs := PartitionErr(fallible(fail[int](errA), val(1), val(2), fail[int](errB), val(3), fail[int](errC)))
+	if d := cmp.Diff(values, []int{1, 2, 3}); d != "" {
+		t.Errorf("values mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(errs, []error{errA, errB, errC}, cmpopts.EquateErrors()); d != "" {
+		t.Errorf("errors mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestWrapErr(t *testing.T) {
+	in := fallible(val(1), fail[int](errA), val(2), fail[int](errB))
+	wrapped := 0
+	wrap := func(err error) error {
+		if err == nil {
+			t.Fatal("wrap called with nil error")
+		}
+		wrapped++
+		return fmt.Errorf("wrapped: %w", err)
+	}
+
+	var (
+		values []int
+		errs   []error
+	)
+	for v, err := range WrapErr(in, wrap) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		values = append(values, v)
+	}
+	if d := cmp.Diff(values, []int{1, 2}); d != "" {
+		t.Errorf("values mismatch (-got, +want):\n%v", d)
+	}
+	if wrapped != 2 {
+		t.Errorf("wrap called %d times, want 2", wrapped)
+	}
+	if len(errs) != 2 || !errors.Is(errs[0], errA) || !errors.Is(errs[1], errB) {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestWrapErrf(t *testing.T) {
+	_, err := CollectErr(WrapErrf(fallible(val(1), fail[int](errA)), "stage %d", 2))
+	if !errors.Is(err, errA) {
+		t.Errorf("got error %v, want one wrapping %v", err, errA)
+	}
+	if got, want := err.Error(), "stage 2: a"; got != want {
+		t.Errorf("got error %q, want %q", got, want)
+	}
+}
+
+func TestFirstErr(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		in         []valueOrErr[int]
+		want       error
+		wantPulled int
+	}{{
+		name:       "empty",
+		want:       nil,
+		wantPulled: 0,
+	}, {
+		name:       "no-errors",
+		in:         []valueOrErr[int]{val(1), val(2), val(3)},
+		want:       nil,
+		wantPulled: 3,
+	}, {
+		name:       "first",
+		in:         []valueOrErr[int]{fail[int](errA), val(1), fail[int](errB)},
+		want:       errA,
+		wantPulled: 1,
+	}, {
+		name:       "middle",
+		in:         []valueOrErr[int]{val(1), fail[int](errB), fail[int](errA), val(1)},
+		want:       errB,
+		wantPulled: 2,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			pulled := 0
+			in := func(yield func(int, error) bool) {
+				for v, err := range fallible(c.in...) {
+					pulled++
+					if !yield(v, err) {
+						return
+					}
+				}
+			}
+			if err := FirstErr(in); err != c.want {
+				t.Errorf("got error %v, want %v", err, c.want)
+			}
+			if pulled != c.wantPulled {
+				t.Errorf("pulled %d elements, want %d", pulled, c.wantPulled)
+			}
+		})
+	}
+}
+
+func TestOnError(t *testing.T) {
+	in := fallible(val(1), fail[int](errA), val(2), fail[int](errB), fail[int](errC))
+	var seen []error
+	record := func(err error) { seen = append(seen, err) }
+
+	// Stop before errC.
+	var got []valueOrErr[int]
+	for v, err := range OnError(in, record) {
+		got = append(got, valueOrErr[int]{v, err})
+		if err == errB {
+			break
+		}
+	}
+	want := []valueOrErr[int]{val(1), fail[int](errA), val(2), fail[int](errB)}
+	if d := cmp.Diff(got, want, cmp.AllowUnexported(valueOrErr[int]{}), cmpopts.EquateErrors()); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(seen, []error{errA, errB}, cmpopts.EquateErrors()); d != "" {
+		t.Errorf("f called with unexpected errors (-got, +want):\n%v", d)
+	}
+}
+
+// scriptedProducer returns a function suitable for Retry that returns the
+// provided results in order, followed by "done" forever.
+func scriptedProducer(script ...valueOrErr[int]) (f func() (int, bool, error), calls *int) {
+	calls = new(int)
+	return func() (int, bool, error) {
+		i := *calls
+		*calls++
+		if i >= len(script) {
+			return 0, false, nil
+		}
+		return script[i].v, true, script[i].err
+	}, calls
+}
+
+func TestRetry(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		script      []valueOrErr[int]
+		attempts    int
+		want        []valueOrErr[int]
+		wantCalls   int
+		wantBackoff []int
+	}{{
+		name:      "no-errors",
+		script:    []valueOrErr[int]{val(1), val(2)},
+		attempts:  3,
+		want:      []valueOrErr[int]{val(1), val(2)},
+		wantCalls: 3,
+	}, {
+		name:        "success-after-retry",
+		script:      []valueOrErr[int]{val(1), fail[int](errA), fail[int](errB), val(2), val(3)},
+		attempts:    3,
+		want:        []valueOrErr[int]{val(1), val(2), val(3)},
+		wantCalls:   6,
+		wantBackoff: []int{1, 2},
+	}, {
+		name:        "exhausted",
+		script:      []valueOrErr[int]{fail[int](errA), fail[int](errB), val(1)},
+		attempts:    2,
+		want:        []valueOrErr[int]{fail[int](errB), val(1)},
+		wantCalls:   4,
+		wantBackoff: []int{1},
+	}, {
+		name:      "single-attempt",
+		script:    []valueOrErr[int]{fail[int](errA), val(1), fail[int](errB)},
+		attempts:  1,
+		want:      []valueOrErr[int]{fail[int](errA), val(1), fail[int](errB)},
+		wantCalls: 4,
+	}, {
+		// The producer finishes while an element is being retried.
+		name:        "done-mid-retry",
+		script:      []valueOrErr[int]{val(1), fail[int](errA)},
+		attempts:    3,
+		want:        []valueOrErr[int]{val(1)},
+		wantCalls:   3,
+		wantBackoff: []int{1},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			f, calls := scriptedProducer(c.script...)
+			var backoffs []int
+			backoff := func(retry int) time.Duration {
+				backoffs = append(backoffs, retry)
+				return 0
+			}
+
+			var got []valueOrErr[int]
+			for v, err := range Retry(f, c.attempts, backoff) {
+				got = append(got, valueOrErr[int]{v, err})
+			}
+			if d := cmp.Diff(got, c.want, cmp.AllowUnexported(valueOrErr[int]{}), cmpopts.EquateErrors()); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if *calls != c.wantCalls {
+				t.Errorf("f called %d times, want %d", *calls, c.wantCalls)
+			}
+			if d := cmp.Diff(backoffs, c.wantBackoff); d != "" {
+				t.Errorf("backoff mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestRetryEarlyBreak(t *testing.T) {
+	f, calls := scriptedProducer(val(1), fail[int](errA), val(2))
+	for range Retry(f, 3, nil) {
+		break
+	}
+	if *calls != 1 {
+		t.Errorf("f called %d times, want 1", *calls)
+	}
+}
+
+func TestValuesOKAndErrs(t *testing.T) {
+	in := fallible(fail[int](errA), val(1), val(2), fail[int](errB), val(3), fail[int](errC))
+
+	if d := cmp.Diff(slices.Collect(ValuesOK(in)), []int{1, 2, 3}); d != "" {
+		t.Errorf("ValuesOK mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(slices.Collect(Errs(in)), []error{errA, errB, errC}, cmpopts.EquateErrors()); d != "" {
+		t.Errorf("Errs mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(slices.Collect(Take(ValuesOK(in), 2)), []int{1, 2}); d != "" {
+		t.Errorf("ValuesOK with Take mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(slices.Collect(Take(Errs(in), 2)), []error{errA, errB}, cmpopts.EquateErrors()); d != "" {
+		t.Errorf("Errs with Take mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestStopOnErr(t *testing.T) {
+	pulled := 0
+	in := func(yield func(int, error) bool) {
+		for v, err := range fallible(val(1), val(2), fail[int](errA), val(3), fail[int](errB)) {
+			pulled++
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+
+	var got []valueOrErr[int]
+	for v, err := range StopOnErr(in) {
+		got = append(got, valueOrErr[int]{v, err})
+	}
+	want := []valueOrErr[int]{val(1), val(2), fail[int](errA)}
+	if d := cmp.Diff(got, want, cmp.AllowUnexported(valueOrErr[int]{}), cmpopts.EquateErrors()); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if pulled != 3 {
+		t.Errorf("pulled %d elements, want 3", pulled)
+	}
+}
+
+func TestLiftErr(t *testing.T) {
+	values := []int{1, 2, 3, 4}
+	got, err := CollectErr(LiftErr(slices.Values(values)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := cmp.Diff(got, values); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+
+	if d := cmp.Diff(slices.Collect(DropNilErr(LiftErr(slices.Values(values)))), values); d != "" {
+		t.Errorf("round trip mismatch (-got, +want):\n%v", d)
+	}
+
+	// Breaking early should propagate through both.
+	pulled := 0
+	in := func(yield func(int) bool) {
+		for _, v := range values {
+			pulled++
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	for v := range DropNilErr(LiftErr(in)) {
+		if v == 2 {
+			break
+		}
+	}
+	if pulled != 2 {
+		t.Errorf("pulled %d elements, want 2", pulled)
+	}
+}
+
+func TestDropNilErrPanics(t *testing.T) {
+	var got []int
+	err := recoverErr(t, func() {
+		for v := range DropNilErr(fallible(val(1), fail[int](errA), val(2))) {
+			got = append(got, v)
+		}
+	})
+	if !errors.Is(err, errA) {
+		t.Errorf("got panic %v, want one wrapping %v", err, errA)
+	}
+	if d := cmp.Diff(got, []int{1}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestCollectErrN(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		in         []valueOrErr[int]
+		n          int
+		want       []int
+		wantErr    error
+		wantPulled int
+	}{{
+		name:       "limit-before-error",
+		in:         []valueOrErr[int]{val(1), val(2), fail[int](errA)},
+		n:          2,
+		want:       []int{1, 2},
+		wantPulled: 2,
+	}, {
+		name:       "error-before-limit",
+		in:         []valueOrErr[int]{val(1), fail[int](errA), val(2)},
+		n:          2,
+		want:       []int{1},
+		wantErr:    errA,
+		wantPulled: 2,
+	}, {
+		name:       "short",
+		in:         []valueOrErr[int]{val(1), val(2)},
+		n:          5,
+		want:       []int{1, 2},
+		wantPulled: 2,
+	}, {
+		name:       "zero",
+		in:         []valueOrErr[int]{val(1), val(2)},
+		n:          0,
+		want:       nil,
+		wantPulled: 0,
+	}, {
+		name:       "unlimited",
+		in:         []valueOrErr[int]{val(1), val(2), val(3), fail[int](errA)},
+		n:          -1,
+		want:       []int{1, 2, 3},
+		wantErr:    errA,
+		wantPulled: 4,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			pulled := 0
+			in := func(yield func(int, error) bool) {
+				for v, err := range fallible(c.in...) {
+					pulled++
+					if !yield(v, err) {
+						return
+					}
+				}
+			}
+			got, err := CollectErrN(in, c.n)
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if err != c.wantErr {
+				t.Errorf("got error %v, want %v", err, c.wantErr)
+			}
+			if pulled != c.wantPulled {
+				t.Errorf("pulled %d elements, want %d", pulled, c.wantPulled)
+			}
+		})
+	}
+}
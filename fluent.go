@@ -0,0 +1,115 @@
+package it
+
+import (
+	"iter"
+	"slices"
+)
+
+// Seq is an iter.Seq with methods for the functions in this package, so that
+// pipelines can be written as a chain of method calls which read in the order
+// they happen:
+//
+//	it.From(xs).Filter(p).Take(10).Collect()
+//
+// Methods can't have their own type parameters, so functions that change the
+// type of the values, such as Map, don't have a method and have to be called
+// directly:
+//
+//	it.From(it.Map(xs, f)).Filter(p).Collect()
+//
+// Each method just calls the function of the same name. A Seq can be ranged
+// over directly, or passed to anything expecting an iter.Seq using Iter.
+type Seq[A any] iter.Seq[A]
+
+// From returns it as a Seq.
+func From[A any](it iter.Seq[A]) Seq[A] { return Seq[A](it) }
+
+// Iter returns s as a plain iter.Seq.
+func (s Seq[A]) Iter() iter.Seq[A] { return iter.Seq[A](s) }
+
+// Filter is Filter(s, p).
+func (s Seq[A]) Filter(p func(A) bool) Seq[A] { return From(Filter(s.Iter(), p)) }
+
+// Take is Take(s, n).
+func (s Seq[A]) Take(n int) Seq[A] { return From(Take(s.Iter(), n)) }
+
+// TakeWhile is TakeWhile(s, p).
+func (s Seq[A]) TakeWhile(p func(A) bool) Seq[A] { return From(TakeWhile(s.Iter(), p)) }
+
+// Drop is Drop(s, n).
+func (s Seq[A]) Drop(n int) Seq[A] { return From(Drop(s.Iter(), n)) }
+
+// Limit is Limit(s, n).
+func (s Seq[A]) Limit(n int) Seq[A] { return From(Limit(s.Iter(), n)) }
+
+// Batch is Batch(s, n). It returns a plain iter.Seq because a Seq[A] method
+// can't return a Seq[[]A], but it can be wrapped again with From.
+func (s Seq[A]) Batch(n int) iter.Seq[[]A] { return Batch(s.Iter(), n) }
+
+// Enumerate is Enumerate(s).
+func (s Seq[A]) Enumerate() Seq2[int, A] { return From2(Enumerate(s.Iter())) }
+
+// Inspect is Inspect(s, f).
+func (s Seq[A]) Inspect(f func(A)) Seq[A] { return From(Inspect(s.Iter(), f)) }
+
+// Chain is Chain(s, others...).
+func (s Seq[A]) Chain(others ...iter.Seq[A]) Seq[A] {
+	return From(Chain(append([]iter.Seq[A]{s.Iter()}, others...)...))
+}
+
+// Collect is slices.Collect(s).
+func (s Seq[A]) Collect() []A { return slices.Collect(s.Iter()) }
+
+// Fold is Fold(s, z, f), except that the accumulated value has to be the same
+// type as the values of s.
+func (s Seq[A]) Fold(z A, f func(A, A) A) A { return Fold(s.Iter(), z, f) }
+
+// Count is Count(s).
+func (s Seq[A]) Count() int { return Count(s.Iter()) }
+
+// ForEach is ForEach(s, f).
+func (s Seq[A]) ForEach(f func(A)) { ForEach(s.Iter(), f) }
+
+// Seq2 is like Seq, but for an iter.Seq2. Methods can't constrain the types of
+// the values either, so terminals which only make sense for some types, such
+// as CollectErr and CollectMap, are functions that take a Seq2 instead:
+//
+//	values, err := it.Seq2CollectErr(it.From2(seq).Take(10))
+type Seq2[A, B any] iter.Seq2[A, B]
+
+// From2 returns it as a Seq2.
+func From2[A, B any](it iter.Seq2[A, B]) Seq2[A, B] { return Seq2[A, B](it) }
+
+// Iter returns s as a plain iter.Seq2.
+func (s Seq2[A, B]) Iter() iter.Seq2[A, B] { return iter.Seq2[A, B](s) }
+
+// Filter2 is Filter2(s, p).
+func (s Seq2[A, B]) Filter2(p func(A, B) bool) Seq2[A, B] { return From2(Filter2(s.Iter(), p)) }
+
+// Keys is Keys(s).
+func (s Seq2[A, B]) Keys() Seq[A] { return From(Keys(s.Iter())) }
+
+// Values is Values(s).
+func (s Seq2[A, B]) Values() Seq[B] { return From(Values(s.Iter())) }
+
+// Swap is Swap(s).
+func (s Seq2[A, B]) Swap() Seq2[B, A] { return From2(Swap(s.Iter())) }
+
+// Take is Take2(s, n).
+func (s Seq2[A, B]) Take(n int) Seq2[A, B] { return From2(Take2(s.Iter(), n)) }
+
+// Inspect2 is Inspect2(s, f).
+func (s Seq2[A, B]) Inspect2(f func(A, B)) Seq2[A, B] { return From2(Inspect2(s.Iter(), f)) }
+
+// Collect2 is Collect2(s).
+func (s Seq2[A, B]) Collect2() []Pair[A, B] { return Collect2(s.Iter()) }
+
+// Fold2 is Fold2(s, z, f), except that the accumulated value has to be the
+// same type as the second values of s.
+func (s Seq2[A, B]) Fold2(z B, f func(A, B, B) B) B { return Fold2(s.Iter(), z, f) }
+
+// Seq2CollectErr is CollectErr(s).
+func Seq2CollectErr[A any](s Seq2[A, error]) ([]A, error) { return CollectErr(s.Iter()) }
+
+// Seq2CollectMap is CollectMap(s).
+func Seq2CollectMap[K comparable, V any](s Seq2[K, V]) map[K]V { return CollectMap(s.Iter()) }
@@ -0,0 +1,250 @@
+package it
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSeq(t *testing.T) {
+	values := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	even := func(v int) bool { return v%2 == 0 }
+	small := func(v int) bool { return v < 5 }
+	for _, c := range []struct {
+		name   string
+		method func(Seq[int]) any
+		free   func(iter.Seq[int]) any
+	}{{
+		name:   "Filter",
+		method: func(s Seq[int]) any { return s.Filter(even).Collect() },
+		free:   func(s iter.Seq[int]) any { return slices.Collect(Filter(s, even)) },
+	}, {
+		name:   "Take",
+		method: func(s Seq[int]) any { return s.Take(3).Collect() },
+		free:   func(s iter.Seq[int]) any { return slices.Collect(Take(s, 3)) },
+	}, {
+		name:   "TakeWhile",
+		method: func(s Seq[int]) any { return s.TakeWhile(small).Collect() },
+		free:   func(s iter.Seq[int]) any { return slices.Collect(TakeWhile(s, small)) },
+	}, {
+		name:   "Drop",
+		method: func(s Seq[int]) any { return s.Drop(7).Collect() },
+		free:   func(s iter.Seq[int]) any { return slices.Collect(Drop(s, 7)) },
+	}, {
+		name:   "Limit",
+		method: func(s Seq[int]) any { return s.Limit(4).Collect() },
+		free:   func(s iter.Seq[int]) any { return slices.Collect(Limit(s, 4)) },
+	}, {
+		name: "Batch",
+		method: func(s Seq[int]) any {
+			return From(s.Batch(3)).Fold(nil, func(b, acc []int) []int { return append(acc, len(b)) })
+		},
+		free: func(s iter.Seq[int]) any {
+			return Fold(Batch(s, 3), []int(nil), func(b, acc []int) []int { return append(acc, len(b)) })
+		},
+	}, {
+		name:   "Enumerate",
+		method: func(s Seq[int]) any { return s.Enumerate().Collect2() },
+		free:   func(s iter.Seq[int]) any { return Collect2(Enumerate(s)) },
+	}, {
+		name: "Inspect",
+		method: func(s Seq[int]) any {
+			var seen []int
+			s.Inspect(func(v int) { seen = append(seen, v) }).Take(3).Count()
+			return seen
+		},
+		free: func(s iter.Seq[int]) any {
+			var seen []int
+			Count(Take(Inspect(s, func(v int) { seen = append(seen, v) }), 3))
+			return seen
+		},
+	}, {
+		name:   "Chain",
+		method: func(s Seq[int]) any { return s.Chain(s.Iter(), slices.Values([]int{0})).Collect() },
+		free:   func(s iter.Seq[int]) any { return slices.Collect(Chain(s, s, slices.Values([]int{0}))) },
+	}, {
+		name:   "Fold",
+		method: func(s Seq[int]) any { return s.Fold(0, func(a, b int) int { return a + b }) },
+		free:   func(s iter.Seq[int]) any { return Fold(s, 0, func(a, b int) int { return a + b }) },
+	}, {
+		name:   "Count",
+		method: func(s Seq[int]) any { return s.Filter(even).Count() },
+		free:   func(s iter.Seq[int]) any { return Count(Filter(s, even)) },
+	}, {
+		name: "ForEach",
+		method: func(s Seq[int]) any {
+			var got []string
+			s.ForEach(func(v int) { got = append(got, fmt.Sprint(v)) })
+			return got
+		},
+		free: func(s iter.Seq[int]) any {
+			var got []string
+			ForEach(s, func(v int) { got = append(got, fmt.Sprint(v)) })
+			return got
+		},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			mSrc, mPulled, mReturned := countingSeq(values...)
+			fSrc, fPulled, fReturned := countingSeq(values...)
+			got, want := c.method(From(mSrc)), c.free(fSrc)
+			if d := cmp.Diff(got, want); d != "" {
+				t.Errorf("method and function differ (-method, +function):\n%v", d)
+			}
+			if *mPulled != *fPulled || *mReturned != *fReturned {
+				t.Errorf("method pulled %d and returned %v, function pulled %d and returned %v",
+					*mPulled, *mReturned, *fPulled, *fReturned)
+			}
+		})
+	}
+}
+
+func TestSeqChaining(t *testing.T) {
+	got := From(naturals()).
+		Filter(func(v int) bool { return v%3 == 0 }).
+		Drop(1).
+		Take(4).
+		Collect()
+	if d := cmp.Diff(got, []int{3, 6, 9, 12}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	// Seqs can be ranged over directly.
+	var ranged []int
+	for v := range From(slices.Values([]int{1, 2})) {
+		ranged = append(ranged, v)
+	}
+	if d := cmp.Diff(ranged, []int{1, 2}); d != "" {
+		t.Errorf("range mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestSeq2(t *testing.T) {
+	pairs := []Pair[string, int]{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}, {"e", 5}}
+	odd := func(_ string, v int) bool { return v%2 == 1 }
+	for _, c := range []struct {
+		name   string
+		method func(Seq2[string, int]) any
+		free   func(iter.Seq2[string, int]) any
+		want   any
+	}{{
+		name:   "Filter2",
+		method: func(s Seq2[string, int]) any { return s.Filter2(odd).Collect2() },
+		free:   func(s iter.Seq2[string, int]) any { return Collect2(Filter2(s, odd)) },
+		want:   []Pair[string, int]{{"a", 1}, {"c", 3}, {"e", 5}},
+	}, {
+		name:   "Keys",
+		method: func(s Seq2[string, int]) any { return s.Keys().Collect() },
+		free:   func(s iter.Seq2[string, int]) any { return slices.Collect(Keys(s)) },
+		want:   []string{"a", "b", "c", "d", "e"},
+	}, {
+		name:   "Values",
+		method: func(s Seq2[string, int]) any { return s.Values().Collect() },
+		free:   func(s iter.Seq2[string, int]) any { return slices.Collect(Values(s)) },
+		want:   []int{1, 2, 3, 4, 5},
+	}, {
+		name:   "Swap",
+		method: func(s Seq2[string, int]) any { return s.Swap().Take(2).Collect2() },
+		free:   func(s iter.Seq2[string, int]) any { return Collect2(Take2(Swap(s), 2)) },
+		want:   []Pair[int, string]{{1, "a"}, {2, "b"}},
+	}, {
+		name:   "Take",
+		method: func(s Seq2[string, int]) any { return s.Take(3).Collect2() },
+		free:   func(s iter.Seq2[string, int]) any { return Collect2(Take2(s, 3)) },
+		want:   []Pair[string, int]{{"a", 1}, {"b", 2}, {"c", 3}},
+	}, {
+		name: "Inspect2",
+		method: func(s Seq2[string, int]) any {
+			var seen []string
+			s.Inspect2(func(k string, _ int) { seen = append(seen, k) }).Take(2).Collect2()
+			return seen
+		},
+		free: func(s iter.Seq2[string, int]) any {
+			var seen []string
+			Collect2(Take2(Inspect2(s, func(k string, _ int) { seen = append(seen, k) }), 2))
+			return seen
+		},
+		want: []string{"a", "b"},
+	}, {
+		name:   "Fold2",
+		method: func(s Seq2[string, int]) any { return s.Fold2(0, func(_ string, v, acc int) int { return acc + v }) },
+		free: func(s iter.Seq2[string, int]) any {
+			return Fold2(s, 0, func(_ string, v, acc int) int { return acc + v })
+		},
+		want: 15,
+	}, {
+		name:   "CollectMap",
+		method: func(s Seq2[string, int]) any { return CollectMap(s.Filter2(odd).Iter()) },
+		free:   func(s iter.Seq2[string, int]) any { return CollectMap(Filter2(s, odd)) },
+		want:   map[string]int{"a": 1, "c": 3, "e": 5},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			mSrc, mPulled, mReturned := countingSeq(pairs...)
+			fSrc, fPulled, fReturned := countingSeq(pairs...)
+			got := c.method(From2(Unpair(mSrc)))
+			if d := cmp.Diff(got, c.free(Unpair(fSrc))); d != "" {
+				t.Errorf("method and function differ (-method, +function):\n%v", d)
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if *mPulled != *fPulled || *mReturned != *fReturned {
+				t.Errorf("method pulled %d and returned %v, function pulled %d and returned %v",
+					*mPulled, *mReturned, *fPulled, *fReturned)
+			}
+		})
+	}
+}
+
+func TestSeq2Pipelines(t *testing.T) {
+	m := map[string]int{"a": 1, "bb": 2, "ccc": 3, "dddd": 4}
+	got := From2(Sorted2ByKey(maps.All(m))).
+		Filter2(func(k string, v int) bool { return len(k) == v && v > 1 }).
+		Keys().
+		Collect()
+	if d := cmp.Diff(got, []string{"bb", "ccc", "dddd"}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+
+	values, err := Seq2CollectErr(From2(fallible(val(1), val(2), fail[int](errA), val(3))).Take(3))
+	if d := cmp.Diff(values, []int{1, 2}); d != "" {
+		t.Errorf("values mismatch (-got, +want):\n%v", d)
+	}
+	if err != errA {
+		t.Errorf("got error %v, want %v", err, errA)
+	}
+
+	enumerated := From(slices.Values([]string{"x", "y"})).Enumerate().Swap().Collect2()
+	if d := cmp.Diff(enumerated, []Pair[string, int]{{"x", 0}, {"y", 1}}); d != "" {
+		t.Errorf("enumerated mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestSeq2Collect(t *testing.T) {
+	for _, in := range [][]valueOrErr[int]{
+		nil,
+		{val(1), val(2), val(3)},
+		{val(1), fail[int](errA), val(3)},
+		{fail[int](errA), fail[int](errB)},
+	} {
+		got, gotErr := Seq2CollectErr(From2(fallible(in...)))
+		want, wantErr := CollectErr(fallible(in...))
+		if d := cmp.Diff(got, want); d != "" {
+			t.Errorf("Seq2CollectErr(%v) mismatch (-got, +want):\n%v", in, d)
+		}
+		if gotErr != wantErr {
+			t.Errorf("Seq2CollectErr(%v) error = %v, want %v", in, gotErr, wantErr)
+		}
+	}
+
+	m := map[string]int{"a": 1, "bb": 2, "ccc": 3}
+	got := Seq2CollectMap(From2(maps.All(m)).Filter2(func(k string, v int) bool { return v > 1 }))
+	if d := cmp.Diff(got, map[string]int{"bb": 2, "ccc": 3}); d != "" {
+		t.Errorf("Seq2CollectMap mismatch (-got, +want):\n%v", d)
+	}
+	if got := Seq2CollectMap(From2(maps.All(map[int]int{}))); len(got) != 0 {
+		t.Errorf("Seq2CollectMap(empty) = %v", got)
+	}
+}
@@ -1,6 +1,11 @@
 package it
 
-import "iter"
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"slices"
+)
 
 // Fold performs a left fold across the iterator using the provided combining
 // function and initial value.
@@ -12,6 +17,15 @@ func Fold[A, B any](it iter.Seq[A], z B, f func(A, B) B) B {
 	return b
 }
 
+// Fold2 is Fold for an iter.Seq2.
+func Fold2[A, B, C any](it iter.Seq2[A, B], z C, f func(A, B, C) C) C {
+	c := z
+	for a, b := range it {
+		c = f(a, b, c)
+	}
+	return c
+}
+
 // All is a specialised fold for iterators of bools that returns true iff all of
 // the values yielded by the iterator are true.
 func All(bs iter.Seq[bool]) bool {
@@ -27,3 +41,166 @@ func All(bs iter.Seq[bool]) bool {
 	}
 	return true
 }
+
+// Count consumes the iterator, returning the number of values it yielded.
+func Count[A any](it iter.Seq[A]) int {
+	n := 0
+	for range it {
+		n++
+	}
+	return n
+}
+
+// IsEmpty reports whether the iterator yields no values. It pulls at most one
+// value and then stops, so for a single use iterator, such as one reading from
+// a stream, that value is lost. Use PeekEmpty to keep it.
+func IsEmpty[A any](it iter.Seq[A]) bool {
+	for range it {
+		return false
+	}
+	return true
+}
+
+// NotEmpty is !IsEmpty(it).
+func NotEmpty[A any](it iter.Seq[A]) bool {
+	return !IsEmpty(it)
+}
+
+// ForEach calls f with every value yielded by the iterator.
+func ForEach[A any](it iter.Seq[A], f func(A)) {
+	for a := range it {
+		f(a)
+	}
+}
+
+// TryFold is like Fold, but the combining function can fail. It stops at the
+// first error, without pulling anything further from the iterator, and returns
+// the accumulated value from before the failing call along with the error.
+func TryFold[A, B any](it iter.Seq[A], z B, f func(A, B) (B, error)) (B, error) {
+	b := z
+	for a := range it {
+		next, err := f(a, b)
+		if err != nil {
+			return b, err
+		}
+		b = next
+	}
+	return b, nil
+}
+
+// TryFold2 is TryFold for an iter.Seq2.
+func TryFold2[A, B, C any](it iter.Seq2[A, B], z C, f func(A, B, C) (C, error)) (C, error) {
+	c := z
+	for a, b := range it {
+		next, err := f(a, b, c)
+		if err != nil {
+			return c, err
+		}
+		c = next
+	}
+	return c, nil
+}
+
+// ArgMax returns the position of the largest value yielded by it, counting
+// from zero, or false if it is empty. If the largest value appears more than
+// once, the position of the first is returned. Values are compared with
+// cmp.Compare, so a NaN is smaller than any other float.
+func ArgMax[A cmp.Ordered](it iter.Seq[A]) (int, bool) {
+	return ArgMaxFunc(it, cmp.Compare[A])
+}
+
+// ArgMaxFunc is like ArgMax, but compares values using cmp.
+func ArgMaxFunc[A any](it iter.Seq[A], cmp func(A, A) int) (int, bool) {
+	return argBest(it, func(a, best A) bool { return cmp(a, best) > 0 })
+}
+
+// ArgMin is like ArgMax, but returns the position of the first occurrence of
+// the smallest value.
+func ArgMin[A cmp.Ordered](it iter.Seq[A]) (int, bool) {
+	return ArgMinFunc(it, cmp.Compare[A])
+}
+
+// ArgMinFunc is like ArgMin, but compares values using cmp.
+func ArgMinFunc[A any](it iter.Seq[A], cmp func(A, A) int) (int, bool) {
+	return argBest(it, func(a, best A) bool { return cmp(a, best) < 0 })
+}
+
+// Mode returns the value that it yields most often, along with the number of
+// times it was yielded. If several values are equally common, the one yielded
+// first wins. The boolean is false if it is empty. It uses memory proportional
+// to the number of distinct values.
+func Mode[A comparable](it iter.Seq[A]) (A, int, bool) {
+	modes, n := Modes(it)
+	if len(modes) == 0 {
+		var zero A
+		return zero, 0, false
+	}
+	return modes[0], n, true
+}
+
+// Modes is like Mode, but returns all of the most common values, in the order
+// they were first yielded.
+func Modes[A comparable](it iter.Seq[A]) ([]A, int) {
+	var (
+		counts = make(map[A]int)
+		// Distinct values in the order they were first seen.
+		seen []A
+		most int
+	)
+	for a := range it {
+		n := counts[a] + 1
+		if n == 1 {
+			seen = append(seen, a)
+		}
+		counts[a] = n
+		most = max(most, n)
+	}
+	var modes []A
+	for _, a := range seen {
+		if counts[a] == most {
+			modes = append(modes, a)
+		}
+	}
+	return modes, most
+}
+
+// Bucketize counts the values of it that fall between each of the bounds,
+// which must be strictly increasing. The result has len(bounds)+1 counts: the
+// first is for values less than bounds[0], the last is for values greater than
+// or equal to the last bound, and the rest count values v with
+// bounds[i-1] <= v < bounds[i] at index i. Values are compared with
+// cmp.Compare, so a NaN is counted as less than bounds[0]. It panics if bounds
+// are not strictly increasing.
+func Bucketize[A cmp.Ordered](it iter.Seq[A], bounds []A) []int {
+	for i := 1; i < len(bounds); i++ {
+		if !(bounds[i-1] < bounds[i]) {
+			panic(fmt.Sprintf("it.Bucketize: bounds not strictly increasing at %d: %v, %v", i, bounds[i-1], bounds[i]))
+		}
+	}
+	counts := make([]int, len(bounds)+1)
+	for a := range it {
+		i, found := slices.BinarySearch(bounds, a)
+		if found {
+			i++
+		}
+		counts[i]++
+	}
+	return counts
+}
+
+// argBest returns the position of the first value from it that nothing after
+// it beats.
+func argBest[A any](it iter.Seq[A], beats func(a, best A) bool) (int, bool) {
+	var (
+		best  A
+		bestI = -1
+		i     = 0
+	)
+	for a := range it {
+		if bestI < 0 || beats(a, best) {
+			best, bestI = a, i
+		}
+		i++
+	}
+	return bestI, bestI >= 0
+}
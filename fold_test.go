@@ -1,6 +1,8 @@
 package it
 
 import (
+	"errors"
+	"math"
 	"slices"
 	"testing"
 
@@ -51,3 +53,275 @@ func TestAll(t *testing.T) {
 		})
 	}
 }
+
+var errTooBig = errors.New("too big")
+
+func TestTryFold(t *testing.T) {
+	sumSmall := func(a, b int) (int, error) {
+		if a > 3 {
+			return -1, errTooBig
+		}
+		return a + b, nil
+	}
+	for _, c := range []struct {
+		name       string
+		in         []int
+		want       int
+		wantErr    error
+		wantPulled int
+	}{{
+		name:       "empty",
+		want:       0,
+		wantPulled: 0,
+	}, {
+		name:       "no-error",
+		in:         []int{1, 2, 3},
+		want:       6,
+		wantPulled: 3,
+	}, {
+		name:       "first",
+		in:         []int{4, 1, 2},
+		want:       0,
+		wantErr:    errTooBig,
+		wantPulled: 1,
+	}, {
+		name:       "middle",
+		in:         []int{1, 2, 5, 1},
+		want:       3,
+		wantErr:    errTooBig,
+		wantPulled: 3,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			pulled := 0
+			in := func(yield func(int) bool) {
+				for _, v := range c.in {
+					pulled++
+					if !yield(v) {
+						return
+					}
+				}
+			}
+			got, err := TryFold(in, 0, sumSmall)
+			if got != c.want || err != c.wantErr {
+				t.Errorf("got (%d, %v), want (%d, %v)", got, err, c.want, c.wantErr)
+			}
+			if pulled != c.wantPulled {
+				t.Errorf("pulled %d elements, want %d", pulled, c.wantPulled)
+			}
+
+			// TryFold2 should do exactly the same thing.
+			pulled = 0
+			got, err = TryFold2(Enumerate(in), 0, func(_, a, b int) (int, error) {
+				return sumSmall(a, b)
+			})
+			if got != c.want || err != c.wantErr {
+				t.Errorf("TryFold2: got (%d, %v), want (%d, %v)", got, err, c.want, c.wantErr)
+			}
+			if pulled != c.wantPulled {
+				t.Errorf("TryFold2: pulled %d elements, want %d", pulled, c.wantPulled)
+			}
+		})
+	}
+}
+
+func TestArgMaxMin(t *testing.T) {
+	for _, c := range []struct {
+		name             string
+		in               []float64
+		wantMax, wantMin int
+		wantOK           bool
+	}{
+		{name: "empty"},
+		{name: "one", in: []float64{1}, wantMax: 0, wantMin: 0, wantOK: true},
+		{name: "increasing", in: []float64{1, 2, 3}, wantMax: 2, wantMin: 0, wantOK: true},
+		{name: "decreasing", in: []float64{3, 2, 1}, wantMax: 0, wantMin: 2, wantOK: true},
+		{name: "ties", in: []float64{2, 1, 3, 1, 3, 2}, wantMax: 2, wantMin: 1, wantOK: true},
+		{name: "all-equal", in: []float64{5, 5, 5}, wantMax: 0, wantMin: 0, wantOK: true},
+		{name: "nan", in: []float64{1, math.NaN(), 2}, wantMax: 2, wantMin: 1, wantOK: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			check := func(name string, got int, ok bool, want int) {
+				t.Helper()
+				if ok != c.wantOK || (ok && got != want) {
+					t.Errorf("%s(%v) = %d, %v, want %d, %v", name, c.in, got, ok, want, c.wantOK)
+				}
+			}
+			i, ok := ArgMax(slices.Values(c.in))
+			check("ArgMax", i, ok, c.wantMax)
+			i, ok = ArgMin(slices.Values(c.in))
+			check("ArgMin", i, ok, c.wantMin)
+		})
+	}
+}
+
+func TestArgMaxMinFunc(t *testing.T) {
+	type sample struct {
+		ID      string
+		Latency int
+	}
+	samples := []sample{{"a", 10}, {"b", 50}, {"c", 5}, {"d", 50}, {"e", 5}}
+	byLatency := func(a, b sample) int { return a.Latency - b.Latency }
+	if i, ok := ArgMaxFunc(slices.Values(samples), byLatency); i != 1 || !ok {
+		t.Errorf("ArgMaxFunc = %d, %v, want 1, true", i, ok)
+	}
+	if i, ok := ArgMinFunc(slices.Values(samples), byLatency); i != 2 || !ok {
+		t.Errorf("ArgMinFunc = %d, %v, want 2, true", i, ok)
+	}
+	if _, ok := ArgMaxFunc(slices.Values([]sample(nil)), byLatency); ok {
+		t.Error("ArgMaxFunc of empty input returned ok")
+	}
+}
+
+func TestMode(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		in        string
+		wantMode  rune
+		wantModes []rune
+		wantN     int
+	}{{
+		name: "empty",
+	}, {
+		name:      "one",
+		in:        "a",
+		wantMode:  'a',
+		wantModes: []rune{'a'},
+		wantN:     1,
+	}, {
+		name:      "clear-winner",
+		in:        "abcbdb",
+		wantMode:  'b',
+		wantModes: []rune{'b'},
+		wantN:     3,
+	}, {
+		name:      "tie-first-seen",
+		in:        "abba",
+		wantMode:  'a',
+		wantModes: []rune{'a', 'b'},
+		wantN:     2,
+	}, {
+		name:      "tie-reached-later",
+		in:        "cbbaac",
+		wantMode:  'c',
+		wantModes: []rune{'c', 'b', 'a'},
+		wantN:     2,
+	}, {
+		name:      "all-distinct",
+		in:        "xyz",
+		wantMode:  'x',
+		wantModes: []rune{'x', 'y', 'z'},
+		wantN:     1,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			mode, n, ok := Mode(slices.Values([]rune(c.in)))
+			if mode != c.wantMode || n != c.wantN || ok != (c.in != "") {
+				t.Errorf("Mode(%q) = %q, %d, %v, want %q, %d, %v", c.in, mode, n, ok, c.wantMode, c.wantN, c.in != "")
+			}
+			modes, n := Modes(slices.Values([]rune(c.in)))
+			if d := cmp.Diff(modes, c.wantModes); d != "" {
+				t.Errorf("Modes(%q) mismatch (-got, +want):\n%v", c.in, d)
+			}
+			if n != c.wantN {
+				t.Errorf("Modes(%q) count = %d, want %d", c.in, n, c.wantN)
+			}
+		})
+	}
+}
+
+func TestBucketize(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		in     []float64
+		bounds []float64
+		want   []int
+	}{{
+		name:   "empty",
+		bounds: []float64{1, 2},
+		want:   []int{0, 0, 0},
+	}, {
+		name: "no-bounds",
+		in:   []float64{-1, 0, 1},
+		want: []int{3},
+	}, {
+		name:   "one-bound",
+		in:     []float64{-1, 0, 0.5, 1},
+		bounds: []float64{0},
+		want:   []int{1, 3},
+	}, {
+		name:   "between",
+		in:     []float64{0.5, 1.5, 1.7, 2.5, 2.9},
+		bounds: []float64{0, 1, 2, 3},
+		want:   []int{0, 1, 2, 2, 0},
+	}, {
+		name:   "on-boundaries",
+		in:     []float64{0, 1, 2, 3},
+		bounds: []float64{0, 1, 2, 3},
+		want:   []int{0, 1, 1, 1, 1},
+	}, {
+		name:   "underflow-overflow",
+		in:     []float64{-10, math.Inf(-1), 10, math.Inf(1), math.NaN()},
+		bounds: []float64{0, 1},
+		want:   []int{3, 0, 2},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got := Bucketize(slices.Values(c.in), c.bounds)
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestBucketizePanics(t *testing.T) {
+	for _, bounds := range [][]int{{1, 1}, {1, 3, 2}, {3, 2, 1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Bucketize(_, %v) didn't panic", bounds)
+				}
+			}()
+			Bucketize(slices.Values([]int{1, 2, 3}), bounds)
+		}()
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Bucketize with a NaN bound didn't panic")
+		}
+	}()
+	Bucketize(slices.Values([]float64{1}), []float64{0, math.NaN(), 1})
+}
+
+func TestCount(t *testing.T) {
+	for _, n := range []int{0, 1, 10} {
+		if got := Count(Limit(naturals(), n)); got != n {
+			t.Errorf("Count(Limit(naturals(), %d)) = %d", n, got)
+		}
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	for _, in := range [][]int{nil, {1}, {1, 2, 3}} {
+		src, pulled, returned := countingSeq(in...)
+		want := len(in) == 0
+		if got := IsEmpty(src); got != want {
+			t.Errorf("IsEmpty(%v) = %v, want %v", in, got, want)
+		}
+		if wantPulled := min(len(in), 1); *pulled != wantPulled {
+			t.Errorf("IsEmpty pulled %d values from %v, want %d", *pulled, in, wantPulled)
+		}
+		if got := NotEmpty(src); got == want {
+			t.Errorf("NotEmpty(%v) = %v, want %v", in, got, !want)
+		}
+		if !*returned {
+			t.Errorf("source %v not stopped", in)
+		}
+	}
+}
+
+func TestForEach(t *testing.T) {
+	var got []string
+	ForEach(slices.Values([]string{"a", "b", "c"}), func(s string) { got = append(got, s) })
+	if d := cmp.Diff(got, []string{"a", "b", "c"}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
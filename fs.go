@@ -0,0 +1,53 @@
+package it
+
+import (
+	"io/fs"
+	"iter"
+)
+
+// WalkEntry is a file or directory found by WalkErr.
+type WalkEntry struct {
+	// Path is the path of the file, including root, as passed to
+	// fs.WalkDirFunc.
+	Path string
+	// Entry describes the file. It is nil if root itself couldn't be
+	// read.
+	Entry fs.DirEntry
+}
+
+// Walk returns an iterator over every file and directory in the tree rooted at
+// root, in lexical order, using fs.WalkDir. Errors are skipped over, so
+// anything that can't be read is silently left out: use WalkErr to see them.
+// Stopping iteration stops the walk immediately.
+func Walk(fsys fs.FS, root string) iter.Seq2[string, fs.DirEntry] {
+	return func(yield func(string, fs.DirEntry) bool) {
+		for e, err := range WalkErr(fsys, root, nil) {
+			if err != nil {
+				continue
+			}
+			if !yield(e.Path, e.Entry) {
+				return
+			}
+		}
+	}
+}
+
+// WalkErr is like Walk, but also yields any errors encountered while walking,
+// along with the file that caused them, and the walk carries on past them. A
+// directory that can't be read is yielded twice: once with a nil error, and
+// once with the error from reading it. If skipDir is non-nil, it is called
+// with every directory after it has been yielded, and if it returns true the
+// walk doesn't descend into the directory.
+func WalkErr(fsys fs.FS, root string, skipDir func(path string, d fs.DirEntry) bool) iter.Seq2[WalkEntry, error] {
+	return func(yield func(WalkEntry, error) bool) {
+		fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+			if !yield(WalkEntry{Path: path, Entry: d}, err) {
+				return fs.SkipAll
+			}
+			if err == nil && d.IsDir() && skipDir != nil && skipDir(path, d) {
+				return fs.SkipDir
+			}
+			return nil
+		})
+	}
+}
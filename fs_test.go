@@ -0,0 +1,164 @@
+package it
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// errFS is a file system that fails to open any of the paths in bad with
+// fs.ErrPermission, and counts how many times Open is called.
+type errFS struct {
+	fs.FS
+	bad    map[string]bool
+	opened int
+}
+
+func (e *errFS) Open(name string) (fs.File, error) {
+	e.opened++
+	if e.bad[name] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return e.FS.Open(name)
+}
+
+func testFS(bad ...string) *errFS {
+	fsys := &errFS{
+		FS: fstest.MapFS{
+			"a/b/c.txt": {},
+			"a/b/d.txt": {},
+			"a/e.txt":   {},
+			"f/g.txt":   {},
+			"h.txt":     {},
+		},
+		bad: make(map[string]bool),
+	}
+	for _, b := range bad {
+		fsys.bad[b] = true
+	}
+	return fsys
+}
+
+func TestWalk(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		fsys fs.FS
+		root string
+		want []string
+	}{{
+		name: "all",
+		fsys: testFS(),
+		root: ".",
+		want: []string{".", "a", "a/b", "a/b/c.txt", "a/b/d.txt", "a/e.txt", "f", "f/g.txt", "h.txt"},
+	}, {
+		name: "subdir",
+		fsys: testFS(),
+		root: "a",
+		want: []string{"a", "a/b", "a/b/c.txt", "a/b/d.txt", "a/e.txt"},
+	}, {
+		name: "file",
+		fsys: testFS(),
+		root: "h.txt",
+		want: []string{"h.txt"},
+	}, {
+		name: "missing",
+		fsys: testFS(),
+		root: "nope",
+	}, {
+		name: "unreadable",
+		fsys: testFS("a/b"),
+		root: ".",
+		want: []string{".", "a", "a/b", "a/e.txt", "f", "f/g.txt", "h.txt"},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			var got []string
+			for p, d := range Walk(c.fsys, c.root) {
+				if d.Name() != path.Base(p) {
+					t.Errorf("path %q has entry named %q", p, d.Name())
+				}
+				got = append(got, p)
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestWalkErr(t *testing.T) {
+	type result struct {
+		Path string
+		Err  bool
+	}
+	for _, c := range []struct {
+		name    string
+		fsys    fs.FS
+		root    string
+		skipDir func(string, fs.DirEntry) bool
+		want    []result
+	}{{
+		name: "unreadable",
+		fsys: testFS("a/b", "f"),
+		root: "a",
+		want: []result{{"a", false}, {"a/b", false}, {"a/b", true}, {"a/e.txt", false}},
+	}, {
+		name: "missing-root",
+		fsys: testFS(),
+		root: "nope",
+		want: []result{{"nope", true}},
+	}, {
+		name: "unreadable-root",
+		fsys: testFS("a"),
+		root: "a",
+		want: []result{{"a", true}},
+	}, {
+		name:    "skip",
+		fsys:    testFS(),
+		root:    ".",
+		skipDir: func(path string, _ fs.DirEntry) bool { return path == "a/b" || path == "f" },
+		want: []result{
+			{".", false}, {"a", false}, {"a/b", false}, {"a/e.txt", false}, {"f", false}, {"h.txt", false},
+		},
+	}, {
+		name:    "skip-root",
+		fsys:    testFS(),
+		root:    ".",
+		skipDir: func(string, fs.DirEntry) bool { return true },
+		want:    []result{{".", false}},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			var got []result
+			for e, err := range WalkErr(c.fsys, c.root, c.skipDir) {
+				if err != nil && !errors.Is(err, fs.ErrPermission) && !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("unexpected error for %q: %v", e.Path, err)
+				}
+				got = append(got, result{e.Path, err != nil})
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestWalkEarlyBreak(t *testing.T) {
+	fsys := testFS()
+	var got []string
+	for path := range Walk(fsys, ".") {
+		got = append(got, path)
+		if path == "a" {
+			break
+		}
+	}
+	if d := cmp.Diff(got, []string{".", "a"}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	// Stat and ReadDir of the root, but nothing inside a.
+	if fsys.opened != 2 {
+		t.Errorf("opened %d files, want 2", fsys.opened)
+	}
+}
@@ -0,0 +1,210 @@
+package it
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sync"
+)
+
+// WithClose returns an iterator over the values of it that calls closeFn once
+// iteration finishes, whether because it was exhausted, the consumer stopped
+// early or something panicked. This is useful for iterators backed by files,
+// network connections and the like. The returned iterator is single use:
+// closeFn is called exactly once, at the end of the first range, and ranging
+// over it again yields nothing. Any error from closeFn is discarded, use
+// CollectClose or CollectErrClose if it matters.
+func WithClose[A any](it iter.Seq[A], closeFn func() error) iter.Seq[A] {
+	var once sync.Once
+	return func(yield func(A) bool) {
+		first := false
+		once.Do(func() { first = true })
+		if !first {
+			return
+		}
+		defer closeFn()
+		for a := range it {
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// CollectClose collects all of the values from it and then calls closeFn,
+// returning its error.
+func CollectClose[A any](it iter.Seq[A], closeFn func() error) ([]A, error) {
+	var values []A
+	for a := range it {
+		values = append(values, a)
+	}
+	return values, closeFn()
+}
+
+// CollectErrClose is like CollectErr, but calls closeFn once it has finished,
+// returning closeFn's error joined with the iteration error, if any.
+func CollectErrClose[A any](it iter.Seq2[A, error], closeFn func() error) ([]A, error) {
+	values, err := CollectErr(it)
+	return values, errors.Join(err, closeFn())
+}
+
+// Lines returns an iterator over the lines read from r, without their trailing
+// "\n" or "\r\n". The last line doesn't need to end with a newline. Lines can
+// be any length. If reading from r fails, the error is yielded as the final
+// element, and any incomplete line read before it is discarded.
+func Lines(r io.Reader) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for line, err := range LinesBytes(r) {
+			if !yield(string(line), err) {
+				return
+			}
+		}
+	}
+}
+
+// LinesBytes is like Lines, but yields each line as a []byte, which avoids
+// allocating for every line. The yielded slice is only valid until the next
+// line is yielded (it is reused between lines). Lines can end with "\n" or
+// "\r\n", but a "\r" at the end of input without a "\n" is kept.
+func LinesBytes(r io.Reader) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		br := bufio.NewReader(r)
+		var line []byte
+		for {
+			chunk, err := br.ReadSlice('\n')
+			line = append(line, chunk...)
+			if err == bufio.ErrBufferFull {
+				// The line is longer than the buffer, keep going.
+				continue
+			}
+			if err != nil && err != io.EOF {
+				yield(nil, err)
+				return
+			}
+			if len(line) > 0 {
+				// Only a "\r" right before the "\n" is part of the
+				// line ending.
+				if l, ok := bytes.CutSuffix(line, []byte("\n")); ok {
+					line = bytes.TrimSuffix(l, []byte("\r"))
+				}
+				if !yield(line, nil) {
+					return
+				}
+				line = line[:0]
+			}
+			if err == io.EOF {
+				return
+			}
+		}
+	}
+}
+
+// Tokens returns an iterator over the tokens read from r and split up by split,
+// using a bufio.Scanner, so that for example bufio.ScanWords iterates over
+// words. Tokens can be up to bufio.MaxScanTokenSize long, see TokensMax to
+// change that. Any error, from r or from split, including a token being too
+// long, is yielded as the final element.
+func Tokens(r io.Reader, split bufio.SplitFunc) iter.Seq2[string, error] {
+	return TokensMax(r, split, bufio.MaxScanTokenSize)
+}
+
+// TokensMax is like Tokens, but allows tokens up to maxTokenSize bytes long. It
+// panics if maxTokenSize is not positive.
+func TokensMax(r io.Reader, split bufio.SplitFunc, maxTokenSize int) iter.Seq2[string, error] {
+	if maxTokenSize <= 0 {
+		panic(fmt.Sprintf("it.TokensMax: invalid max token size %d", maxTokenSize))
+	}
+	return func(yield func(string, error) bool) {
+		s := bufio.NewScanner(r)
+		s.Split(split)
+		s.Buffer(make([]byte, 0, min(maxTokenSize, 4096)), maxTokenSize)
+		for s.Scan() {
+			if !yield(s.Text(), nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield("", err)
+		}
+	}
+}
+
+// WriteLines writes each string from it to w followed by a newline, through a
+// bufio.Writer which is flushed before returning. It stops at the first error
+// writing to w, and returns it along with the number of bytes that made it to
+// w.
+func WriteLines(w io.Writer, it iter.Seq[string]) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for s := range it {
+		if _, err := bw.WriteString(s); err != nil {
+			return cw.n, err
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return cw.n, err
+		}
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// WriteTo is like WriteLines, but writes []bytes with nothing between them.
+func WriteTo(w io.Writer, it iter.Seq[[]byte]) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for b := range it {
+		if _, err := bw.Write(b); err != nil {
+			return cw.n, err
+		}
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ReadChunks returns an iterator over successive chunks of size bytes read from
+// r. Every chunk is full, even if r returns short reads, apart from the last
+// which may be smaller. If reading from r fails with an error other than io.EOF
+// the error is yielded as the final element, and any partial chunk read before
+// it is discarded. The yielded slice is only valid until the next chunk is
+// yielded (it is reused between chunks, like Batch). ReadChunks panics if size
+// is not positive.
+func ReadChunks(r io.Reader, size int) iter.Seq2[[]byte, error] {
+	if size <= 0 {
+		panic(fmt.Sprintf("it.ReadChunks: invalid chunk size %d", size))
+	}
+	return func(yield func([]byte, error) bool) {
+		buf := make([]byte, size)
+		for {
+			n, err := io.ReadFull(r, buf)
+			switch err {
+			case nil:
+				if !yield(buf, nil) {
+					return
+				}
+			case io.ErrUnexpectedEOF:
+				yield(buf[:n], nil)
+				return
+			case io.EOF:
+				return
+			default:
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,545 @@
+package it
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// closer counts how many times it has been closed, and returns err every time.
+type closer struct {
+	closed int
+	err    error
+}
+
+func (c *closer) Close() error {
+	c.closed++
+	return c.err
+}
+
+var errWrite = errors.New("write failed")
+
+// brokenWriter accepts n bytes and then fails every write with errWrite,
+// including the one that goes over the limit, which is written short.
+type brokenWriter struct {
+	bytes.Buffer
+	n int
+}
+
+func (w *brokenWriter) Write(p []byte) (int, error) {
+	if left := w.n - w.Len(); len(p) > left {
+		w.Buffer.Write(p[:max(left, 0)])
+		return max(left, 0), errWrite
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestWithClose(t *testing.T) {
+	values := []int{1, 2, 3}
+	for _, c := range []struct {
+		name  string
+		limit int
+		want  []int
+	}{
+		{name: "exhausted", limit: 10, want: values},
+		{name: "early-break", limit: 2, want: []int{1, 2}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var cl closer
+			seq := WithClose(slices.Values(values), cl.Close)
+			if cl.closed != 0 {
+				t.Fatal("closed before ranging")
+			}
+			if d := cmp.Diff(slices.Collect(Limit(seq, c.limit)), c.want); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+			if cl.closed != 1 {
+				t.Fatalf("closed %d times, want 1", cl.closed)
+			}
+			if got := slices.Collect(seq); got != nil {
+				t.Fatalf("second range yielded %v", got)
+			}
+			if cl.closed != 1 {
+				t.Fatalf("closed %d times, want 1", cl.closed)
+			}
+		})
+	}
+}
+
+func TestWithClosePanic(t *testing.T) {
+	var cl closer
+	func() {
+		defer func() { recover() }()
+		for range WithClose(slices.Values([]int{1, 2}), cl.Close) {
+			panic("oh no")
+		}
+	}()
+	if cl.closed != 1 {
+		t.Fatalf("closed %d times, want 1", cl.closed)
+	}
+}
+
+func TestCollectClose(t *testing.T) {
+	errClose := errors.New("close")
+	cl := closer{err: errClose}
+	got, err := CollectClose(slices.Values([]int{1, 2}), cl.Close)
+	if d := cmp.Diff(got, []int{1, 2}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if err != errClose || cl.closed != 1 {
+		t.Fatalf("got error %v after %d closes, want %v after 1", err, cl.closed, errClose)
+	}
+}
+
+func TestCollectErrClose(t *testing.T) {
+	errClose := errors.New("close")
+	for _, c := range []struct {
+		name     string
+		in       []valueOrErr[int]
+		closeErr error
+		want     []int
+		wantErrs []error
+	}{{
+		name: "no-errors",
+		in:   []valueOrErr[int]{val(1), val(2)},
+		want: []int{1, 2},
+	}, {
+		name:     "close-error",
+		in:       []valueOrErr[int]{val(1), val(2)},
+		closeErr: errClose,
+		want:     []int{1, 2},
+		wantErrs: []error{errClose},
+	}, {
+		name:     "iteration-error",
+		in:       []valueOrErr[int]{val(1), fail[int](errA), val(2)},
+		want:     []int{1},
+		wantErrs: []error{errA},
+	}, {
+		name:     "both",
+		in:       []valueOrErr[int]{val(1), fail[int](errA), val(2)},
+		closeErr: errClose,
+		want:     []int{1},
+		wantErrs: []error{errA, errClose},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			cl := closer{err: c.closeErr}
+			got, err := CollectErrClose(fallible(c.in...), cl.Close)
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if cl.closed != 1 {
+				t.Errorf("closed %d times, want 1", cl.closed)
+			}
+			if (err == nil) != (len(c.wantErrs) == 0) {
+				t.Fatalf("got error %v, want %v", err, c.wantErrs)
+			}
+			for _, want := range c.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("got error %v, want one including %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	errRead := errors.New("read")
+	for _, c := range []struct {
+		name    string
+		r       io.Reader
+		want    []string
+		wantErr error
+	}{{
+		name: "empty",
+		r:    strings.NewReader(""),
+	}, {
+		name: "trailing-newline",
+		r:    strings.NewReader("a\nbb\nccc\n"),
+		want: []string{"a", "bb", "ccc"},
+	}, {
+		name: "no-trailing-newline",
+		r:    strings.NewReader("a\nbb\nccc"),
+		want: []string{"a", "bb", "ccc"},
+	}, {
+		name: "crlf",
+		r:    strings.NewReader("a\r\nbb\r\n\r\nccc"),
+		want: []string{"a", "bb", "", "ccc"},
+	}, {
+		name: "blank-lines",
+		r:    strings.NewReader("\n\na\n\n"),
+		want: []string{"", "", "a", ""},
+	}, {
+		name: "long",
+		r:    strings.NewReader("a\n" + long + "\r\nb"),
+		want: []string{"a", long, "b"},
+	}, {
+		name:    "error",
+		r:       io.MultiReader(strings.NewReader("a\nb\nc"), iotest.ErrReader(errRead)),
+		want:    []string{"a", "b"},
+		wantErr: errRead,
+	}, {
+		name: "one-byte-reader",
+		r:    iotest.OneByteReader(strings.NewReader("a\r\nbb\nccc")),
+		want: []string{"a", "bb", "ccc"},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := CollectErr(Lines(c.r))
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if err != c.wantErr {
+				t.Errorf("got error %v, want %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestLinesBytes(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   string
+		want []string
+	}{{
+		name: "empty",
+		in:   "",
+	}, {
+		name: "lf",
+		in:   "one\ntwo\nthree\n",
+		want: []string{"one", "two", "three"},
+	}, {
+		name: "no-trailing-newline",
+		in:   "one\ntwo\nthree",
+		want: []string{"one", "two", "three"},
+	}, {
+		name: "crlf",
+		in:   "one\r\ntwo\r\n",
+		want: []string{"one", "two"},
+	}, {
+		name: "unterminated-cr",
+		in:   "one\r\nabc\r",
+		want: []string{"one", "abc\r"},
+	}, {
+		name: "cr-inside-line",
+		in:   "a\rb\n\r\n",
+		want: []string{"a\rb", ""},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			var got []string
+			for line, err := range LinesBytes(strings.NewReader(c.in)) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got = append(got, string(line))
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func BenchmarkLines(b *testing.B) {
+	input := strings.Repeat("some reasonably long line of text\n", 1000)
+	b.Run("Lines", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			for range Lines(strings.NewReader(input)) {
+			}
+		}
+	})
+	b.Run("LinesBytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			for range LinesBytes(strings.NewReader(input)) {
+			}
+		}
+	})
+}
+
+func TestTokens(t *testing.T) {
+	errSplit := errors.New("split")
+	// Splits on commas, but fails on an 'x'.
+	commas := func(data []byte, atEOF bool) (int, []byte, error) {
+		for i, b := range data {
+			switch b {
+			case 'x':
+				return 0, nil, errSplit
+			case ',':
+				return i + 1, data[:i], nil
+			}
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+	for _, c := range []struct {
+		name    string
+		r       io.Reader
+		split   bufio.SplitFunc
+		want    []string
+		wantErr error
+	}{{
+		name:  "words",
+		r:     strings.NewReader("  the quick\n brown\tfox  "),
+		split: bufio.ScanWords,
+		want:  []string{"the", "quick", "brown", "fox"},
+	}, {
+		name:  "custom",
+		r:     strings.NewReader("a,b,,c"),
+		split: commas,
+		want:  []string{"a", "b", "", "c"},
+	}, {
+		name:    "split-error",
+		r:       strings.NewReader("a,b,x,c"),
+		split:   commas,
+		want:    []string{"a", "b"},
+		wantErr: errSplit,
+	}, {
+		name:    "too-long",
+		r:       strings.NewReader("a " + strings.Repeat("b", bufio.MaxScanTokenSize+1)),
+		split:   bufio.ScanWords,
+		want:    []string{"a"},
+		wantErr: bufio.ErrTooLong,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := CollectErr(Tokens(c.r, c.split))
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if err != c.wantErr {
+				t.Errorf("got error %v, want %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestTokensMax(t *testing.T) {
+	long := strings.Repeat("b", bufio.MaxScanTokenSize+1)
+	got, err := CollectErr(TokensMax(strings.NewReader("a "+long), bufio.ScanWords, 1<<20))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := cmp.Diff(got, []string{"a", long}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestTokensMaxPanics(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("TokensMax(r, split, %d) didn't panic", n)
+				}
+			}()
+			TokensMax(strings.NewReader(""), bufio.ScanWords, n)
+		}()
+	}
+}
+
+func TestTokensEarlyBreak(t *testing.T) {
+	// Reads one byte at a time, so we can tell how far it got.
+	r := strings.NewReader("one two three four")
+	for range Tokens(iotest.OneByteReader(r), bufio.ScanWords) {
+		break
+	}
+	if r.Len() < len("three four") {
+		t.Fatalf("read too far, %d bytes left", r.Len())
+	}
+}
+
+func TestWriteLines(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{name: "empty", want: ""},
+		{name: "one", lines: []string{"one"}, want: "one\n"},
+		{name: "empty-lines", lines: []string{"", "", ""}, want: "\n\n\n"},
+		{name: "several", lines: []string{"one", "two", "three"}, want: "one\ntwo\nthree\n"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var buf strings.Builder
+			n, err := WriteLines(&buf, slices.Values(c.lines))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != c.want {
+				t.Errorf("wrote %q, want %q", got, c.want)
+			}
+			if n != int64(len(c.want)) {
+				t.Errorf("returned n = %d, want %d", n, len(c.want))
+			}
+			// Should round trip.
+			got, err := CollectErr(Lines(strings.NewReader(buf.String())))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(got, c.lines, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("round trip mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	var buf bytes.Buffer
+	chunks := [][]byte{[]byte("one"), nil, []byte("two\n"), []byte("three")}
+	n, err := WriteTo(&buf, slices.Values(chunks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "onetwo\nthree"; got != want {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("returned n = %d, want %d", n, buf.Len())
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	line := strings.Repeat("x", 999)
+	lines := slices.Repeat([]string{line}, 100)
+	chunks := slices.Repeat([][]byte{[]byte(line + "\n")}, 100)
+	for _, c := range []struct {
+		name      string
+		n         int
+		count     int
+		maxPulled int
+	}{
+		// Fails once the bufio.Writer's buffer fills up, writing short.
+		{name: "mid-stream", n: 2500, count: 100, maxPulled: 10},
+		// Everything fits in the buffer, so only the final flush fails.
+		{name: "flush", n: 10, count: 2, maxPulled: 2},
+	} {
+		for _, w := range []struct {
+			name  string
+			write func(io.Writer) (int64, int, error)
+		}{{
+			name: "lines",
+			write: func(w io.Writer) (int64, int, error) {
+				seq, pulled, _ := countingSeq(lines[:c.count]...)
+				n, err := WriteLines(w, seq)
+				return n, *pulled, err
+			},
+		}, {
+			name: "bytes",
+			write: func(w io.Writer) (int64, int, error) {
+				seq, pulled, _ := countingSeq(chunks[:c.count]...)
+				n, err := WriteTo(w, seq)
+				return n, *pulled, err
+			},
+		}} {
+			t.Run(c.name+"/"+w.name, func(t *testing.T) {
+				bw := &brokenWriter{n: c.n}
+				n, pulled, err := w.write(bw)
+				if !errors.Is(err, errWrite) {
+					t.Errorf("got error %v, want %v", err, errWrite)
+				}
+				if n != int64(c.n) || bw.Len() != c.n {
+					t.Errorf("returned n = %d and wrote %d bytes, want %d", n, bw.Len(), c.n)
+				}
+				if pulled > c.maxPulled {
+					t.Errorf("pulled %d values, want at most %d", pulled, c.maxPulled)
+				}
+			})
+		}
+	}
+}
+
+func TestReadChunks(t *testing.T) {
+	data := "abcdefghij"
+	for _, c := range []struct {
+		name string
+		r    func() io.Reader
+	}{
+		{name: "reader", r: func() io.Reader { return strings.NewReader(data) }},
+		{name: "one-byte", r: func() io.Reader { return iotest.OneByteReader(strings.NewReader(data)) }},
+		{name: "data-err", r: func() io.Reader { return iotest.DataErrReader(strings.NewReader(data)) }},
+		{name: "half", r: func() io.Reader { return iotest.HalfReader(strings.NewReader(data)) }},
+	} {
+		for _, s := range []struct {
+			size int
+			want []string
+		}{
+			{size: 1, want: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}},
+			{size: 3, want: []string{"abc", "def", "ghi", "j"}},
+			{size: 5, want: []string{"abcde", "fghij"}},
+			{size: 10, want: []string{"abcdefghij"}},
+			{size: 11, want: []string{"abcdefghij"}},
+		} {
+			t.Run(fmt.Sprintf("%s/%d", c.name, s.size), func(t *testing.T) {
+				var got []string
+				for chunk, err := range ReadChunks(c.r(), s.size) {
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					got = append(got, string(chunk))
+				}
+				if d := cmp.Diff(got, s.want); d != "" {
+					t.Errorf("mismatch (-got, +want):\n%v", d)
+				}
+			})
+		}
+	}
+}
+
+func TestReadChunksEmpty(t *testing.T) {
+	for range ReadChunks(strings.NewReader(""), 4) {
+		t.Fatal("yielded a chunk from an empty reader")
+	}
+}
+
+func TestReadChunksError(t *testing.T) {
+	errRead := errors.New("read")
+	r := io.MultiReader(strings.NewReader("abcdefg"), iotest.ErrReader(errRead))
+	var got []string
+	var err error
+	for chunk, e := range ReadChunks(r, 3) {
+		if e != nil {
+			err = e
+			continue
+		}
+		got = append(got, string(chunk))
+	}
+	// The partial "g" chunk is dropped.
+	if d := cmp.Diff(got, []string{"abc", "def"}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if !errors.Is(err, errRead) {
+		t.Errorf("got error %v, want %v", err, errRead)
+	}
+}
+
+func TestReadChunksEarlyBreak(t *testing.T) {
+	r := strings.NewReader("abcdefghij")
+	for chunk := range Map2x1(ReadChunks(r, 3), func(b []byte, _ error) string { return string(b) }) {
+		if chunk == "def" {
+			break
+		}
+	}
+	if r.Len() != 4 {
+		t.Errorf("%d bytes left unread, want 4", r.Len())
+	}
+}
+
+func TestReadChunksPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("ReadChunks(r, 0) didn't panic")
+		}
+	}()
+	ReadChunks(strings.NewReader(""), 0)
+}
@@ -1,21 +1,33 @@
 // package it provides some tools for working with iterators, inspired by
 // Python's itertools.
+//
+// Functions that take a count of values, such as Take and Limit, panic if it
+// is negative unless documented otherwise. This happens when they are
+// called, rather than when the returned iterator is used. A count of zero
+// means no values.
 package it
 
 import (
+	"fmt"
 	"iter"
+	"maps"
+	"runtime"
 	"slices"
+	"strings"
+	"sync"
 )
 
 // Zip returns an iterator that iterates through a and b at the same time,
 // yielding pairs of adjacent items. The returned iterator stops as soon as
-// either as or bs runs out of items.
+// either as or bs runs out of items. Each pair is made by taking a value from
+// as and then one from bs, so if as runs out first nothing extra is taken from
+// bs, but if bs runs out first the last value taken from as is dropped.
 func Zip[A, B any](as iter.Seq[A], bs iter.Seq[B]) iter.Seq2[A, B] {
 	return func(yield func(A, B) bool) {
-		nextA, stopA := iter.Pull(as)
-		defer stopA()
-		for b := range bs {
-			a, ok := nextA()
+		nextB, stopB := iter.Pull(bs)
+		defer stopB()
+		for a := range as {
+			b, ok := nextB()
 			if !ok {
 				return
 			}
@@ -26,16 +38,47 @@ func Zip[A, B any](as iter.Seq[A], bs iter.Seq[B]) iter.Seq2[A, B] {
 	}
 }
 
+// ZipWith3 returns an iterator that goes through as, bs and cs at the same
+// time, yielding the result of calling f with each trio of values. Like Zip, it
+// stops as soon as any of them runs out, taking values from as first.
+func ZipWith3[A, B, C, D any](as iter.Seq[A], bs iter.Seq[B], cs iter.Seq[C], f func(A, B, C) D) iter.Seq[D] {
+	return func(yield func(D) bool) {
+		nextB, stopB := iter.Pull(bs)
+		defer stopB()
+		nextC, stopC := iter.Pull(cs)
+		defer stopC()
+		for a := range as {
+			b, ok := nextB()
+			if !ok {
+				return
+			}
+			c, ok := nextC()
+			if !ok {
+				return
+			}
+			if !yield(f(a, b, c)) {
+				return
+			}
+		}
+	}
+}
+
 // Enumerate returns an iterator that pairs each element in the provided
 // sequence with its index in the sequence, starting from 0.
 func Enumerate[A any](it iter.Seq[A]) iter.Seq2[int, A] {
+	return EnumerateFrom(it, 0, 1)
+}
+
+// EnumerateFrom is like Enumerate, but the indices start from start and go up
+// by step each time. step can be zero or negative.
+func EnumerateFrom[A any](it iter.Seq[A], start, step int) iter.Seq2[int, A] {
 	return func(yield func(int, A) bool) {
-		j := 0
+		j := start
 		for i := range it {
 			if !yield(j, i) {
 				return
 			}
-			j++
+			j += step
 		}
 	}
 }
@@ -60,14 +103,53 @@ func Concat[A any](its iter.Seq[iter.Seq[A]]) iter.Seq[A] {
 	}
 }
 
+// Prepend returns an iterator that yields vs followed by the values of it. It
+// doesn't start on it until all of vs have been yielded.
+func Prepend[A any](it iter.Seq[A], vs ...A) iter.Seq[A] {
+	return Chain(slices.Values(vs), it)
+}
+
+// Append returns an iterator that yields the values of it followed by vs.
+func Append[A any](it iter.Seq[A], vs ...A) iter.Seq[A] {
+	return Chain(it, slices.Values(vs))
+}
+
+// Coalesce returns an iterator that yields the values of the first of its that
+// yields anything. The iterators are tried in order, and once one has yielded a
+// value the rest are never started.
+func Coalesce[A any](its ...iter.Seq[A]) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for _, it := range its {
+			empty := true
+			for a := range it {
+				empty = false
+				if !yield(a) {
+					return
+				}
+			}
+			if !empty {
+				return
+			}
+		}
+	}
+}
+
+// DefaultIfEmpty returns an iterator that yields the values of it, or def if it
+// doesn't yield anything.
+func DefaultIfEmpty[A any](it iter.Seq[A], def ...A) iter.Seq[A] {
+	return Coalesce(it, slices.Values(def))
+}
+
 // Batch returns an iterator that yields batches of n consecutive values from
 // the provided iterator. The last batch may be smaller. The yielded slice is
-// only valid until the next value is yields (it is reused between batches).
+// only valid until the next value is yields (it is reused between batches),
+// use BatchCloned to get batches that can be kept. It panics if n is not
+// positive.
 func Batch[A any](i iter.Seq[A], n int) iter.Seq[[]A] {
+	if n <= 0 {
+		panic(fmt.Sprintf("it.Batch: invalid batch size %d", n))
+	}
 	return func(yield func([]A) bool) {
-		if n == 0 {
-			return
-		}
 		batch := make([]A, 0, n)
 		for a := range i {
 			batch = append(batch, a)
@@ -84,10 +166,128 @@ func Batch[A any](i iter.Seq[A], n int) iter.Seq[[]A] {
 	}
 }
 
+// BatchCloned is like Batch, but every batch is a newly allocated slice, which
+// the consumer is free to keep, modify or append to.
+func BatchCloned[A any](i iter.Seq[A], n int) iter.Seq[[]A] {
+	if n <= 0 {
+		panic(fmt.Sprintf("it.BatchCloned: invalid batch size %d", n))
+	}
+	return func(yield func([]A) bool) {
+		batch := make([]A, 0, n)
+		for a := range i {
+			batch = append(batch, a)
+			if len(batch) == n {
+				if !yield(batch) {
+					return
+				}
+				batch = make([]A, 0, n)
+			}
+		}
+		if len(batch) > 0 {
+			yield(batch)
+		}
+	}
+}
+
+// BatchByWeight returns an iterator that yields batches of consecutive values
+// from it, each as large as possible without the total of weight over the
+// values in it going over maxWeight. A value which weighs more than maxWeight
+// by itself gets a batch of its own. weight is called once for each value.
+// Like Batch, the yielded slice is reused between batches. It panics if
+// maxWeight is not positive.
+func BatchByWeight[A any](it iter.Seq[A], maxWeight int, weight func(A) int) iter.Seq[[]A] {
+	if maxWeight <= 0 {
+		panic(fmt.Sprintf("it.BatchByWeight: invalid max weight %d", maxWeight))
+	}
+	return func(yield func([]A) bool) {
+		var (
+			batch []A
+			total int
+		)
+		flush := func() bool {
+			ok := yield(batch)
+			batch, total = batch[:0], 0
+			return ok
+		}
+		for a := range it {
+			w := weight(a)
+			if len(batch) > 0 && total+w > maxWeight && !flush() {
+				return
+			}
+			batch = append(batch, a)
+			total += w
+		}
+		if len(batch) > 0 {
+			yield(batch)
+		}
+	}
+}
+
+// SplitN collects the values of it and splits them into n contiguous chunks
+// whose lengths differ by at most one, with the longer chunks first. If there
+// are fewer than n values, the chunks at the end are empty. The chunks share
+// memory, but appending to one doesn't affect the others. It panics if n is
+// not positive.
+func SplitN[A any](it iter.Seq[A], n int) [][]A {
+	if n <= 0 {
+		panic(fmt.Sprintf("it.SplitN: invalid number of chunks %d", n))
+	}
+	values := slices.Collect(it)
+	size, extra := len(values)/n, len(values)%n
+	chunks := make([][]A, n)
+	start := 0
+	for i := range chunks {
+		end := start + size
+		if i < extra {
+			end++
+		}
+		chunks[i] = values[start:end:end]
+		start = end
+	}
+	return chunks
+}
+
+// Transpose collects rows and returns their columns, so that the i'th column
+// holds the i'th element of every row. The rows aren't kept, so it is fine for
+// them to reuse memory, like the batches from Batch. It returns an error if the
+// rows are not all the same length. If there are no rows, there are no
+// columns.
+func Transpose[E any, S ~[]E](rows iter.Seq[S]) ([][]E, error) {
+	var (
+		values []E
+		width  int
+		n      int
+	)
+	for row := range rows {
+		if n == 0 {
+			width = len(row)
+		}
+		if len(row) != width {
+			return nil, fmt.Errorf("it.Transpose: row %d has %d elements, want %d", n, len(row), width)
+		}
+		values = append(values, row...)
+		n++
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	cols := make([][]E, width)
+	flat := make([]E, len(values))
+	for j := range cols {
+		col := flat[j*n : (j+1)*n : (j+1)*n]
+		for i := range col {
+			col[i] = values[i*width+j]
+		}
+		cols[j] = col
+	}
+	return cols, nil
+}
+
 // Limit returns a new iterator that yields the first n values from the provided
 // iterator and then stops. If the parent iterator has fewer than n values, the
 // returned child iterator will just stop when it runs out.
 func Limit[A any](i iter.Seq[A], n int) iter.Seq[A] {
+	checkCount("it.Limit", n)
 	return func(yield func(A) bool) {
 		if n == 0 {
 			return
@@ -114,6 +314,20 @@ func Map[A, B any](as iter.Seq[A], f func(A) B) iter.Seq[B] {
 	}
 }
 
+// MapIndexed is like Map, but f is also passed the position of each item in as,
+// starting from 0.
+func MapIndexed[A, B any](as iter.Seq[A], f func(int, A) B) iter.Seq[B] {
+	return func(yield func(B) bool) {
+		i := 0
+		for a := range as {
+			if !yield(f(i, a)) {
+				return
+			}
+			i++
+		}
+	}
+}
+
 // Map1x2 maps an iter.Seq to an iter.Seq2 by applying the provided function to
 // each item in turn.
 func Map1x2[A, B, C any](as iter.Seq[A], f func(A) (B, C)) iter.Seq2[B, C] {
@@ -150,6 +364,35 @@ func Map2x2[A, B, C, D any](abs iter.Seq2[A, B], f func(A, B) (C, D)) iter.Seq2[
 	}
 }
 
+// MapKeys applies f to the first item of each pair in the iter.Seq2, leaving
+// the second alone. For example, to lower case the keys of a map:
+//
+//	lower := it.MapKeys(maps.All(m), strings.ToLower)
+func MapKeys[A, B, C any](it iter.Seq2[A, B], f func(A) C) iter.Seq2[C, B] {
+	return func(yield func(C, B) bool) {
+		for a, b := range it {
+			if !yield(f(a), b) {
+				return
+			}
+		}
+	}
+}
+
+// MapValues applies f to the second item of each pair in the iter.Seq2,
+// leaving the first alone. For example, to get the lengths of the values of a
+// map:
+//
+//	lengths := it.MapValues(maps.All(m), func(s string) int { return len(s) })
+func MapValues[A, B, C any](it iter.Seq2[A, B], f func(B) C) iter.Seq2[A, C] {
+	return func(yield func(A, C) bool) {
+		for a, b := range it {
+			if !yield(a, f(b)) {
+				return
+			}
+		}
+	}
+}
+
 // Const returns an iterator that continually yields the provided value,
 // forever. Note that this is an infinite iterator, intended to be used with
 // something like Zip or Take that will stop early.
@@ -160,19 +403,72 @@ func Const[A any](a A) iter.Seq[A] {
 	}
 }
 
+// ConstFunc returns an infinite iterator that yields the result of calling f,
+// calling it once for each value, just before the value is yielded. Like
+// Const, it never finishes by itself, so it is meant to be combined with
+// something like Zip, Take or TakeWhile.
+func ConstFunc[A any](f func() A) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for yield(f()) {
+		}
+	}
+}
+
 // Take returns an iterator that yields at most the first n elements of the
-// provided iterator and then stops.
+// provided iterator and then stops, without pulling anything further from it.
 func Take[A any](it iter.Seq[A], n int) iter.Seq[A] {
+	checkCount("it.Take", n)
 	return func(yield func(A) bool) {
+		if n == 0 {
+			return
+		}
 		i := 0
 		for a := range it {
-			if i >= n {
+			if !yield(a) {
+				return
+			}
+			i++
+			if i == n {
 				return
 			}
+		}
+	}
+}
+
+// Drop returns an iterator that skips the first n elements of the provided
+// iterator and yields the rest.
+func Drop[A any](it iter.Seq[A], n int) iter.Seq[A] {
+	checkCount("it.Drop", n)
+	return func(yield func(A) bool) {
+		i := 0
+		for a := range it {
+			if i < n {
+				i++
+				continue
+			}
 			if !yield(a) {
 				return
 			}
+		}
+	}
+}
+
+// Take2 is Take for an iter.Seq2.
+func Take2[A, B any](it iter.Seq2[A, B], n int) iter.Seq2[A, B] {
+	checkCount("it.Take2", n)
+	return func(yield func(A, B) bool) {
+		if n == 0 {
+			return
+		}
+		i := 0
+		for a, b := range it {
+			if !yield(a, b) {
+				return
+			}
 			i++
+			if i == n {
+				return
+			}
 		}
 	}
 }
@@ -208,6 +504,180 @@ func Filter[A any](it iter.Seq[A], p func(A) bool) iter.Seq[A] {
 	}
 }
 
+// Inspect returns an iterator that yields the same values as it, calling f with
+// each one just before it is yielded. It is useful for debugging and logging
+// the values flowing through a pipeline.
+func Inspect[A any](it iter.Seq[A], f func(A)) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for a := range it {
+			f(a)
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// Progress returns an iterator that yields the same values as it, calling
+// report with the number of values yielded so far after every n of them, and
+// with the final count when iteration finishes, including when the consumer
+// stops early, unless that count has just been reported. report is called
+// between values, once the consumer has finished with the last one, so never at
+// the same time as the consumer is running. It panics if n is not positive.
+func Progress[A any](it iter.Seq[A], n int, report func(done int)) iter.Seq[A] {
+	if n <= 0 {
+		panic(fmt.Sprintf("it.Progress: invalid interval %d", n))
+	}
+	return func(yield func(A) bool) {
+		done, reported := 0, -1
+		for a := range it {
+			done++
+			if !yield(a) {
+				break
+			}
+			if done%n == 0 {
+				report(done)
+				reported = done
+			}
+		}
+		if reported != done {
+			report(done)
+		}
+	}
+}
+
+// FilterIndexed is like Filter, but p is also passed the position of each value
+// in it, starting from 0. The position counts every value from it, including
+// the ones that have been filtered out.
+func FilterIndexed[A any](it iter.Seq[A], p func(int, A) bool) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		i := -1
+		for a := range it {
+			i++
+			if !p(i, a) {
+				continue
+			}
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// Filter2 is Filter for an iter.Seq2.
+func Filter2[A, B any](it iter.Seq2[A, B], p func(A, B) bool) iter.Seq2[A, B] {
+	return func(yield func(A, B) bool) {
+		for a, b := range it {
+			if !p(a, b) {
+				continue
+			}
+			if !yield(a, b) {
+				return
+			}
+		}
+	}
+}
+
+// FilterKeys is like Filter2, but p only looks at the first value of each pair.
+func FilterKeys[A, B any](it iter.Seq2[A, B], p func(A) bool) iter.Seq2[A, B] {
+	return Filter2(it, func(a A, _ B) bool { return p(a) })
+}
+
+// FilterValues is like Filter2, but p only looks at the second value of each
+// pair.
+func FilterValues[A, B any](it iter.Seq2[A, B], p func(B) bool) iter.Seq2[A, B] {
+	return Filter2(it, func(_ A, b B) bool { return p(b) })
+}
+
+// FilterKeysIn returns an iterator over the pairs from it whose first value is
+// in set.
+func FilterKeysIn[A comparable, B any](it iter.Seq2[A, B], set map[A]struct{}) iter.Seq2[A, B] {
+	return FilterKeys(it, func(a A) bool {
+		_, ok := set[a]
+		return ok
+	})
+}
+
+// UniqueKeysFirst returns an iterator over the pairs from it, skipping any
+// pair whose first value has been seen before, so that the first value for
+// each key wins. It remembers every distinct key, so uses memory proportional
+// to their number.
+func UniqueKeysFirst[K comparable, V any](it iter.Seq2[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		seen := make(map[K]struct{})
+		for k, v := range it {
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// UniqueKeysLast is like UniqueKeysFirst, but the last value for each key
+// wins. The keys are yielded in the order they were first seen. This means
+// reading all of it before yielding anything, and holding on to one value for
+// each distinct key.
+func UniqueKeysLast[K comparable, V any](it iter.Seq2[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var (
+			keys   []K
+			values = make(map[K]V)
+		)
+		for k, v := range it {
+			if _, ok := values[k]; !ok {
+				keys = append(keys, k)
+			}
+			values[k] = v
+		}
+		for _, k := range keys {
+			if !yield(k, values[k]) {
+				return
+			}
+		}
+	}
+}
+
+// Inspect2 is Inspect for an iter.Seq2.
+func Inspect2[A, B any](it iter.Seq2[A, B], f func(A, B)) iter.Seq2[A, B] {
+	return func(yield func(A, B) bool) {
+		for a, b := range it {
+			f(a, b)
+			if !yield(a, b) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over just the first of each pair of values yielded
+// by it.
+func Keys[A, B any](it iter.Seq2[A, B]) iter.Seq[A] {
+	return Map2x1(it, func(a A, _ B) A { return a })
+}
+
+// Values returns an iterator over just the second of each pair of values
+// yielded by it.
+func Values[A, B any](it iter.Seq2[A, B]) iter.Seq[B] {
+	return Map2x1(it, func(_ A, b B) B { return b })
+}
+
+// Swap returns an iterator that yields the pairs of values from it the other
+// way around.
+func Swap[A, B any](it iter.Seq2[A, B]) iter.Seq2[B, A] {
+	return Map2x2(it, func(a A, b B) (B, A) { return b, a })
+}
+
+// checkCount panics if n is negative, see the package documentation.
+func checkCount(name string, n int) {
+	if n < 0 {
+		panic(fmt.Sprintf("%s: negative count %d", name, n))
+	}
+}
+
 // Pair is just a pair of two elements, for occasions where we need to do things
 // like collect the values in an iter.Seq2.
 type Pair[A, B any] struct {
@@ -228,8 +698,113 @@ func Collect2[A, B any](i iter.Seq2[A, B]) []Pair[A, B] {
 	return slices.Collect(Map2x1(i, NewPair))
 }
 
+// CollectMap collects the pairs of values from it into a map. If a key appears
+// more than once, the last value for it wins. It is the same as maps.Collect.
+func CollectMap[K comparable, V any](it iter.Seq2[K, V]) map[K]V {
+	return maps.Collect(it)
+}
+
 // Unpair is a convenience for turning an iter.Seq[Pair[A, B]] into an
 // iter.Seq2[A, B].
 func Unpair[A, B any](i iter.Seq[Pair[A, B]]) iter.Seq2[A, B] {
 	return Map1x2(i, Pair[A, B].Values)
 }
+
+// Once returns an iterator that yields the same values as it, but panics if it
+// is ranged over more than once. This is a debugging aid for catching
+// single-use iterators being reused, which otherwise tends to just look like
+// an empty iterator. The panic message includes the stack from the first time
+// it was ranged over.
+func Once[A any](it iter.Seq[A]) iter.Seq[A] {
+	var o once
+	return func(yield func(A) bool) {
+		o.check("it.Once")
+		for a := range it {
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// Once2 is Once for an iter.Seq2.
+func Once2[A, B any](it iter.Seq2[A, B]) iter.Seq2[A, B] {
+	var o once
+	return func(yield func(A, B) bool) {
+		o.check("it.Once2")
+		for a, b := range it {
+			if !yield(a, b) {
+				return
+			}
+		}
+	}
+}
+
+// once records where an iterator was first ranged over.
+type once struct {
+	mu    sync.Mutex
+	first []uintptr
+}
+
+// check records the caller's stack the first time it is called, and panics
+// with a description of that stack every time after that.
+func (o *once) check(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.first == nil {
+		pcs := make([]uintptr, 32)
+		// Skip runtime.Callers, check and the iterator function.
+		o.first = pcs[:runtime.Callers(3, pcs)]
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: iterator ranged over more than once, first ranged over at:", name)
+	frames := runtime.CallersFrames(o.first)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "\n\t%s\n\t\t%s:%d", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	panic(b.String())
+}
+
+// RunLength returns an iterator that collapses each run of equal consecutive
+// values of it into a single pair of the value and the length of the run.
+func RunLength[A comparable](it iter.Seq[A]) iter.Seq2[A, int] {
+	return func(yield func(A, int) bool) {
+		var (
+			cur A
+			n   int
+		)
+		for a := range it {
+			if n > 0 && a == cur {
+				n++
+				continue
+			}
+			if n > 0 && !yield(cur, n) {
+				return
+			}
+			cur, n = a, 1
+		}
+		if n > 0 {
+			yield(cur, n)
+		}
+	}
+}
+
+// RunLengthDecode is the inverse of RunLength: it yields each value of it as
+// many times as the count it is paired with. Values with a count of zero or
+// less are skipped.
+func RunLengthDecode[A any](it iter.Seq2[A, int]) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for a, n := range it {
+			for range n {
+				if !yield(a) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -1,12 +1,18 @@
 package it
 
 import (
+	"fmt"
 	"iter"
+	"maps"
+	"math/rand/v2"
 	"slices"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pfcm/it/seqtest"
 )
 
 func TestZip(t *testing.T) {
@@ -56,6 +62,74 @@ func TestZip(t *testing.T) {
 	}
 }
 
+func TestZipPulls(t *testing.T) {
+	for _, c := range []struct {
+		name          string
+		as, bs, limit int
+		wantA, wantB  int
+	}{
+		{name: "first-shorter", as: 3, bs: 5, wantA: 3, wantB: 3},
+		{name: "second-shorter", as: 5, bs: 3, wantA: 4, wantB: 3},
+		{name: "equal", as: 4, bs: 4, wantA: 4, wantB: 4},
+		{name: "first-empty", as: 0, bs: 4, wantA: 0, wantB: 0},
+		{name: "early-break", as: 5, bs: 5, limit: 2, wantA: 2, wantB: 2},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			pa := seqtest.NewProbe(Limit(naturals(), c.as))
+			pb := seqtest.NewProbe(Limit(naturals(), c.bs))
+			n := 0
+			for range Zip(pa.Seq(), pb.Seq()) {
+				n++
+				if n == c.limit {
+					break
+				}
+			}
+			if pa.Pulled() != c.wantA || pb.Pulled() != c.wantB {
+				t.Errorf("pulled %d from as and %d from bs, want %d and %d", pa.Pulled(), pb.Pulled(), c.wantA, c.wantB)
+			}
+			if pa.Running() || pb.Running() {
+				t.Errorf("sources still running: as %v, bs %v", pa.Running(), pb.Running())
+			}
+		})
+	}
+}
+
+func TestZipWith3(t *testing.T) {
+	format := func(a int, b string, c bool) string { return fmt.Sprintf("%d%s %v", a, b, c) }
+	for _, c := range []struct {
+		name       string
+		as, bs, cs int
+		want       []string
+	}{
+		{name: "equal", as: 3, bs: 3, cs: 3, want: []string{"0a true", "1b false", "2c true"}},
+		{name: "first-shortest", as: 1, bs: 3, cs: 3, want: []string{"0a true"}},
+		{name: "second-shortest", as: 3, bs: 2, cs: 3, want: []string{"0a true", "1b false"}},
+		{name: "third-shortest", as: 3, bs: 3, cs: 0},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			pa := seqtest.NewProbe(Limit(naturals(), c.as))
+			pb := seqtest.NewProbe(Take(slices.Values([]string{"a", "b", "c"}), c.bs))
+			pc := seqtest.NewProbe(Limit(ConstFunc(alternate()), c.cs))
+			got := slices.Collect(ZipWith3(pa.Seq(), pb.Seq(), pc.Seq(), format))
+			if d := cmp.Diff(got, c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if pa.Running() || pb.Running() || pc.Running() {
+				t.Errorf("sources still running: %v, %v, %v", pa.Running(), pb.Running(), pc.Running())
+			}
+		})
+	}
+}
+
+// alternate returns a function that returns true, false, true...
+func alternate() func() bool {
+	b := false
+	return func() bool {
+		b = !b
+		return b
+	}
+}
+
 func TestChain(t *testing.T) {
 	values := []int{1, 2, 3, 4, 5}
 	for i := range 10 {
@@ -90,9 +164,6 @@ func TestBatch(t *testing.T) {
 		n    int
 		want [][]int
 	}{{
-		n:    0,
-		want: nil,
-	}, {
 		n:    1,
 		want: [][]int{{1}, {2}, {3}, {4}, {5}},
 	}, {
@@ -126,6 +197,146 @@ func TestBatch(t *testing.T) {
 	}
 }
 
+func TestBatchInvalidSize(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		batch func(iter.Seq[int], int) iter.Seq[[]int]
+	}{
+		{name: "Batch", batch: Batch[int]},
+		{name: "BatchCloned", batch: BatchCloned[int]},
+	} {
+		for _, n := range []int{0, -5} {
+			t.Run(fmt.Sprintf("%s/%d", c.name, n), func(t *testing.T) {
+				defer func() {
+					r := recover()
+					msg, _ := r.(string)
+					if want := fmt.Sprintf("it.%s: invalid batch size %d", c.name, n); msg != want {
+						t.Errorf("got panic %v, want %q", r, want)
+					}
+				}()
+				c.batch(slices.Values([]int{1, 2, 3}), n)
+				t.Error("no panic")
+			})
+		}
+	}
+}
+
+func TestBatchCloned(t *testing.T) {
+	in := []int{1, 2, 3, 4, 5, 6, 7}
+	for n := 1; n <= len(in)+1; n++ {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			var want [][]int
+			for b := range Batch(slices.Values(in), n) {
+				want = append(want, slices.Clone(b))
+			}
+			// No cloning needed.
+			got := slices.Collect(BatchCloned(slices.Values(in), n))
+			if d := cmp.Diff(got, want); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+			// Writing to one batch, even past its end, doesn't
+			// affect any of the others.
+			for i := range got {
+				for j := range got[i] {
+					got[i][j] = i
+				}
+				got[i] = append(got[i], i)
+			}
+			for i, b := range got {
+				for _, v := range b {
+					if v != i {
+						t.Fatalf("batch %d was modified through another batch: %v", i, b)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBatchByWeight(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   []string
+		max  int
+		want [][]string
+	}{{
+		name: "empty",
+		max:  5,
+	}, {
+		name: "all-fit",
+		in:   []string{"a", "b", "c"},
+		max:  5,
+		want: [][]string{{"a", "b", "c"}},
+	}, {
+		name: "exact-fit",
+		in:   []string{"ab", "cde", "fg", "hij", "k"},
+		max:  5,
+		want: [][]string{{"ab", "cde"}, {"fg", "hij"}, {"k"}},
+	}, {
+		name: "one-over",
+		in:   []string{"ab", "cd", "ef", "gh"},
+		max:  5,
+		want: [][]string{{"ab", "cd"}, {"ef", "gh"}},
+	}, {
+		name: "oversized",
+		in:   []string{"a", "bcdefgh", "i", "jklmnopq", "rstuvwxyz"},
+		max:  5,
+		want: [][]string{{"a"}, {"bcdefgh"}, {"i"}, {"jklmnopq"}, {"rstuvwxyz"}},
+	}, {
+		name: "zero-weight",
+		in:   []string{"", "abcde", "", "", "fg"},
+		max:  5,
+		want: [][]string{{"", "abcde", "", ""}, {"fg"}},
+	}, {
+		name: "exact-fit-then-zero-weight",
+		in:   []string{"ab", "cde", "", "f", "ghijk", ""},
+		max:  5,
+		want: [][]string{{"ab", "cde", ""}, {"f"}, {"ghijk", ""}},
+	}, {
+		name: "oversized-then-zero-weight",
+		in:   []string{"abcdefg", "", "h"},
+		max:  5,
+		want: [][]string{{"abcdefg"}, {"", "h"}},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			calls := 0
+			weight := func(s string) int {
+				calls++
+				return len(s)
+			}
+			var got [][]string
+			for b := range BatchByWeight(slices.Values(c.in), c.max, weight) {
+				got = append(got, slices.Clone(b))
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if calls != len(c.in) {
+				t.Errorf("weight called %d times for %d values", calls, len(c.in))
+			}
+		})
+	}
+}
+
+func TestBatchByWeightEarlyBreak(t *testing.T) {
+	p := seqtest.NewProbe(naturals())
+	var got [][]int
+	for b := range BatchByWeight(p.Seq(), 10, func(v int) int { return v }) {
+		got = append(got, slices.Clone(b))
+		if len(got) == 3 {
+			break
+		}
+	}
+	// 0+1+2+3+4 = 10 fills the first batch exactly, 5 is the second and 6
+	// is the third, which is yielded when 7 doesn't fit.
+	if d := cmp.Diff(got, [][]int{{0, 1, 2, 3, 4}, {5}, {6}}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if p.Pulled() != 8 || p.Running() {
+		t.Errorf("pulled %d values, running %v, want 8, false", p.Pulled(), p.Running())
+	}
+}
+
 func TestLimit(t *testing.T) {
 	data := []int{1, 2, 3, 4, 5, 6}
 	for i := range len(data) + 2 {
@@ -238,9 +449,6 @@ func TestTake(t *testing.T) {
 	}, {
 		n:    0,
 		want: nil,
-	}, {
-		n:    -1,
-		want: nil,
 	}} {
 		t.Run(strconv.Itoa(c.n), func(t *testing.T) {
 			got := slices.Collect(Take(slices.Values(values), c.n))
@@ -251,6 +459,32 @@ func TestTake(t *testing.T) {
 	}
 }
 
+func TestTakeLimitPulls(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		f    func(iter.Seq[int], int) iter.Seq[int]
+	}{
+		{name: "Take", f: Take[int]},
+		{name: "Limit", f: Limit[int]},
+	} {
+		for _, n := range []int{0, 1, 3, 10} {
+			t.Run(fmt.Sprintf("%s/%d", c.name, n), func(t *testing.T) {
+				p := seqtest.NewProbe(naturals())
+				got := slices.Collect(c.f(p.Seq(), n))
+				if len(got) != n {
+					t.Errorf("got %d values, want %d", len(got), n)
+				}
+				if p.Pulled() != n {
+					t.Errorf("pulled %d values from the source, want exactly %d", p.Pulled(), n)
+				}
+				if p.Running() {
+					t.Error("source still running")
+				}
+			})
+		}
+	}
+}
+
 func TestTakeWhile(t *testing.T) {
 	values := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
 	for _, c := range []struct {
@@ -314,3 +548,955 @@ func TestFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestOnce(t *testing.T) {
+	values := []int{1, 2, 3}
+	for _, c := range []struct {
+		name string
+		seq  func() iter.Seq[int]
+	}{{
+		name: "Once",
+		seq:  func() iter.Seq[int] { return Once(slices.Values(values)) },
+	}, {
+		name: "Once2",
+		seq: func() iter.Seq[int] {
+			return Map2x1(Once2(Enumerate(slices.Values(values))), func(_, v int) int { return v })
+		},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			seq := c.seq()
+			if d := cmp.Diff(slices.Collect(seq), values); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+			defer func() {
+				r := recover()
+				msg, ok := r.(string)
+				if !ok {
+					t.Fatalf("expected string panic, got %v", r)
+				}
+				// The first range happened in this function.
+				if !strings.Contains(msg, "TestOnce") || !strings.Contains(msg, "it_test.go") {
+					t.Fatalf("panic doesn't say where the first range was:\n%s", msg)
+				}
+			}()
+			for range seq {
+			}
+			t.Fatal("expected panic")
+		})
+	}
+}
+
+func TestRunLength(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   string
+		want []Pair[rune, int]
+	}{
+		{name: "empty"},
+		{name: "one", in: "a", want: []Pair[rune, int]{{'a', 1}}},
+		{name: "one-run", in: "aaaa", want: []Pair[rune, int]{{'a', 4}}},
+		{name: "no-runs", in: "abc", want: []Pair[rune, int]{{'a', 1}, {'b', 1}, {'c', 1}}},
+		{name: "mixed", in: "aaabccddddaa", want: []Pair[rune, int]{{'a', 3}, {'b', 1}, {'c', 2}, {'d', 4}, {'a', 2}}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got := Collect2(RunLength(slices.Values([]rune(c.in))))
+			if d := cmp.Diff(got, c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if decoded := string(slices.Collect(RunLengthDecode(Unpair(slices.Values(got))))); decoded != c.in {
+				t.Errorf("decoded %q, want %q", decoded, c.in)
+			}
+		})
+	}
+}
+
+func TestRunLengthDecodeSkipsNonPositive(t *testing.T) {
+	in := []Pair[string, int]{{"a", 2}, {"b", 0}, {"c", -3}, {"d", 1}}
+	got := slices.Collect(RunLengthDecode(Unpair(slices.Values(in))))
+	if d := cmp.Diff(got, []string{"a", "a", "d"}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestRunLengthRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewPCG(7, 8))
+	for range 200 {
+		in := make([]int, r.IntN(50))
+		for i := range in {
+			in[i] = r.IntN(3)
+		}
+		got := slices.Collect(RunLengthDecode(RunLength(slices.Values(in))))
+		if d := cmp.Diff(got, in, cmpopts.EquateEmpty()); d != "" {
+			t.Fatalf("round trip of %v mismatch (-got, +want):\n%v", in, d)
+		}
+	}
+}
+
+func TestRunLengthUnbounded(t *testing.T) {
+	// Runs of length 1, 2, 3... forever.
+	runs := func(yield func(int) bool) {
+		for i := 1; ; i++ {
+			for range i {
+				if !yield(i) {
+					return
+				}
+			}
+		}
+	}
+	var got []Pair[int, int]
+	for v, n := range RunLength(runs) {
+		got = append(got, NewPair(v, n))
+		if len(got) == 4 {
+			break
+		}
+	}
+	if d := cmp.Diff(got, []Pair[int, int]{{1, 1}, {2, 2}, {3, 3}, {4, 4}}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	values := []int{1, 2, 3, 4}
+	for _, c := range []struct {
+		n    int
+		want []int
+	}{
+		{n: 0, want: values},
+		{n: 1, want: []int{2, 3, 4}},
+		{n: 3, want: []int{4}},
+		{n: 4},
+		{n: 10},
+	} {
+		t.Run(strconv.Itoa(c.n), func(t *testing.T) {
+			got := slices.Collect(Drop(slices.Values(values), c.n))
+			if d := cmp.Diff(got, c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	var seen []int
+	var got []int
+	for v := range Inspect(slices.Values([]int{1, 2, 3, 4}), func(v int) { seen = append(seen, v) }) {
+		// f is called before the value is yielded.
+		if seen[len(seen)-1] != v {
+			t.Errorf("yielded %d before inspecting it", v)
+		}
+		got = append(got, v)
+		if v == 3 {
+			break
+		}
+	}
+	if d := cmp.Diff(seen, []int{1, 2, 3}); d != "" {
+		t.Errorf("inspected mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(got, []int{1, 2, 3}); d != "" {
+		t.Errorf("yielded mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		n    int
+		in   []int
+		stop int
+		want []string
+	}{{
+		name: "empty",
+		n:    2,
+		want: []string{"report 0"},
+	}, {
+		name: "every-value",
+		n:    1,
+		in:   []int{1, 2},
+		want: []string{"got 1", "report 1", "got 2", "report 2"},
+	}, {
+		name: "exact-multiple",
+		n:    2,
+		in:   []int{1, 2, 3, 4},
+		want: []string{"got 1", "got 2", "report 2", "got 3", "got 4", "report 4"},
+	}, {
+		name: "partial",
+		n:    2,
+		in:   []int{1, 2, 3, 4, 5},
+		want: []string{"got 1", "got 2", "report 2", "got 3", "got 4", "report 4", "got 5", "report 5"},
+	}, {
+		name: "early-break",
+		n:    2,
+		in:   []int{1, 2, 3, 4, 5},
+		stop: 4,
+		want: []string{"got 1", "got 2", "report 2", "got 3", "got 4", "report 4"},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			var events []string
+			report := func(done int) { events = append(events, fmt.Sprint("report ", done)) }
+			for v := range Progress(slices.Values(c.in), c.n, report) {
+				events = append(events, fmt.Sprint("got ", v))
+				if v == c.stop {
+					break
+				}
+			}
+			if d := cmp.Diff(events, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestEarlyTermination(t *testing.T) {
+	even := func(v int) bool { return v%2 == 0 }
+	for _, c := range []struct {
+		name       string
+		adapt      func(iter.Seq[int]) iter.Seq[int]
+		wantPulled int
+	}{
+		{name: "Take", adapt: func(s iter.Seq[int]) iter.Seq[int] { return Take(s, 10) }, wantPulled: 3},
+		{name: "Limit", adapt: func(s iter.Seq[int]) iter.Seq[int] { return Limit(s, 10) }, wantPulled: 3},
+		{name: "Filter", adapt: func(s iter.Seq[int]) iter.Seq[int] { return Filter(s, even) }, wantPulled: 5},
+		{name: "TakeWhile", adapt: func(s iter.Seq[int]) iter.Seq[int] {
+			return TakeWhile(s, func(v int) bool { return v < 10 })
+		}, wantPulled: 3},
+		{name: "Drop", adapt: func(s iter.Seq[int]) iter.Seq[int] { return Drop(s, 2) }, wantPulled: 5},
+		{name: "Map", adapt: func(s iter.Seq[int]) iter.Seq[int] {
+			return Map(s, func(v int) int { return v * 2 })
+		}, wantPulled: 3},
+		{name: "Enumerate", adapt: func(s iter.Seq[int]) iter.Seq[int] {
+			return Map2x1(Enumerate(s), func(i, v int) int { return i + v })
+		}, wantPulled: 3},
+		{name: "Batch", adapt: func(s iter.Seq[int]) iter.Seq[int] {
+			return Map(Batch(s, 2), func(b []int) int { return b[0] })
+		}, wantPulled: 6},
+		{name: "Inspect", adapt: func(s iter.Seq[int]) iter.Seq[int] { return Inspect(s, func(int) {}) }, wantPulled: 3},
+		{name: "Chain", adapt: func(s iter.Seq[int]) iter.Seq[int] {
+			return Chain(slices.Values([]int{-1}), s)
+		}, wantPulled: 2},
+		{name: "RunLength", adapt: func(s iter.Seq[int]) iter.Seq[int] {
+			return Map2x1(RunLength(s), func(v, _ int) int { return v })
+		}, wantPulled: 4},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			p := seqtest.NewProbe(naturals())
+			n := 0
+			for range seqtest.Brittle(c.adapt(p.Seq())) {
+				n++
+				if n == 3 {
+					break
+				}
+			}
+			if p.Running() || !p.Stopped() {
+				t.Errorf("source not stopped: running %v, stopped %v", p.Running(), p.Stopped())
+			}
+			if p.Pulled() != c.wantPulled {
+				t.Errorf("pulled %d values from the source, want %d", p.Pulled(), c.wantPulled)
+			}
+		})
+	}
+}
+
+func TestCounts(t *testing.T) {
+	values := []int{1, 2, 3, 4}
+	// Each function's output is flattened into a slice of ints.
+	for _, c := range []struct {
+		name string
+		f    func(iter.Seq[int], int) []int
+		want func(n int) []int
+		// Whether zero is invalid too.
+		positive bool
+	}{{
+		name: "Take",
+		f:    func(s iter.Seq[int], n int) []int { return slices.Collect(Take(s, n)) },
+		want: func(n int) []int { return values[:min(n, len(values))] },
+	}, {
+		name: "Take2",
+		f: func(s iter.Seq[int], n int) []int {
+			return slices.Collect(Keys(Take2(Map1x2(s, func(v int) (int, int) { return v, v }), n)))
+		},
+		want: func(n int) []int { return values[:min(n, len(values))] },
+	}, {
+		name: "Limit",
+		f:    func(s iter.Seq[int], n int) []int { return slices.Collect(Limit(s, n)) },
+		want: func(n int) []int { return values[:min(n, len(values))] },
+	}, {
+		name: "Drop",
+		f:    func(s iter.Seq[int], n int) []int { return slices.Collect(Drop(s, n)) },
+		want: func(n int) []int { return values[min(n, len(values)):] },
+	}, {
+		name: "Batch",
+		f: func(s iter.Seq[int], n int) []int {
+			var sizes []int
+			for b := range Batch(s, n) {
+				sizes = append(sizes, len(b))
+			}
+			return sizes
+		},
+		want: func(n int) []int {
+			var sizes []int
+			for len(sizes)*n < len(values) {
+				sizes = append(sizes, min(n, len(values)-len(sizes)*n))
+			}
+			return sizes
+		},
+		positive: true,
+	}, {
+		name: "Tee",
+		f:    func(s iter.Seq[int], n int) []int { return []int{len(Tee(s, n))} },
+		want: func(n int) []int { return []int{n} },
+	}, {
+		name: "NextN",
+		f: func(s iter.Seq[int], n int) []int {
+			next, stop := iter.Pull(s)
+			defer stop()
+			return NextN(next, n)
+		},
+		want: func(n int) []int { return values[:min(n, len(values))] },
+	}} {
+		for _, n := range []int{-1, 0, 1, len(values), len(values) + 1} {
+			t.Run(fmt.Sprintf("%s/%d", c.name, n), func(t *testing.T) {
+				invalid := n < 0 || (n == 0 && c.positive)
+				if invalid {
+					defer func() {
+						r := recover()
+						msg, _ := r.(string)
+						if !strings.HasPrefix(msg, "it."+c.name+": ") {
+							t.Errorf("got panic %v, want one from it.%s", r, c.name)
+						}
+					}()
+				}
+				got := c.f(slices.Values(values), n)
+				if invalid {
+					t.Fatalf("no panic for invalid count, got %v", got)
+				}
+				if d := cmp.Diff(got, c.want(n), cmpopts.EquateEmpty()); d != "" {
+					t.Errorf("mismatch (-got, +want):\n%v", d)
+				}
+			})
+		}
+	}
+}
+
+func TestConstFunc(t *testing.T) {
+	calls := 0
+	counter := func() int {
+		calls++
+		return calls * 10
+	}
+	seq := ConstFunc(counter)
+	if calls != 0 {
+		t.Fatalf("f called %d times before ranging", calls)
+	}
+	got := slices.Collect(Take(seq, 3))
+	if d := cmp.Diff(got, []int{10, 20, 30}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if calls != 3 {
+		t.Errorf("f called %d times for 3 values", calls)
+	}
+
+	// Zipped with a shorter sequence, f is called once per pair.
+	calls = 0
+	pairs := Collect2(Zip(slices.Values([]string{"a", "b"}), seq))
+	if d := cmp.Diff(pairs, []Pair[string, int]{{"a", 10}, {"b", 20}}); d != "" {
+		t.Errorf("zip mismatch (-got, +want):\n%v", d)
+	}
+	if calls != 2 {
+		t.Errorf("f called %d times for 2 pairs", calls)
+	}
+}
+
+func TestSplitN(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		len  int
+		n    int
+		want [][]int
+	}{
+		{name: "empty", len: 0, n: 2, want: [][]int{{}, {}}},
+		{name: "fewer", len: 2, n: 4, want: [][]int{{0}, {1}, {}, {}}},
+		{name: "equal", len: 3, n: 3, want: [][]int{{0}, {1}, {2}}},
+		{name: "divisible", len: 6, n: 3, want: [][]int{{0, 1}, {2, 3}, {4, 5}}},
+		{name: "not-divisible", len: 8, n: 3, want: [][]int{{0, 1, 2}, {3, 4, 5}, {6, 7}}},
+		{name: "one", len: 4, n: 1, want: [][]int{{0, 1, 2, 3}}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got := SplitN(Limit(naturals(), c.len), c.n)
+			if d := cmp.Diff(got, c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+			// Appending to a chunk leaves the next one alone.
+			for i := range got {
+				got[i] = append(got[i], -1)
+			}
+			for i := range got {
+				if d := cmp.Diff(got[i][:len(got[i])-1], c.want[i], cmpopts.EquateEmpty()); d != "" {
+					t.Errorf("chunk %d changed by appending (-got, +want):\n%v", i, d)
+				}
+			}
+		})
+	}
+}
+
+func TestEnumerateFrom(t *testing.T) {
+	values := []string{"a", "b", "c"}
+	for _, c := range []struct {
+		name        string
+		start, step int
+		want        []int
+	}{
+		{name: "default", start: 0, step: 1, want: []int{0, 1, 2}},
+		{name: "from-one", start: 1, step: 1, want: []int{1, 2, 3}},
+		{name: "stride", start: 100, step: 10, want: []int{100, 110, 120}},
+		{name: "down", start: 2, step: -1, want: []int{2, 1, 0}},
+		{name: "negative", start: -1, step: -2, want: []int{-1, -3, -5}},
+		{name: "zero-step", start: 7, step: 0, want: []int{7, 7, 7}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var want []Pair[int, string]
+			for i, v := range values {
+				want = append(want, NewPair(c.want[i], v))
+			}
+			got := Collect2(EnumerateFrom(slices.Values(values), c.start, c.step))
+			if d := cmp.Diff(got, want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestEnumerateFromEarlyBreak(t *testing.T) {
+	p := seqtest.NewProbe(naturals())
+	var got []int
+	for i := range EnumerateFrom(p.Seq(), 1, 1) {
+		got = append(got, i)
+		if i == 3 {
+			break
+		}
+	}
+	if d := cmp.Diff(got, []int{1, 2, 3}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if p.Pulled() != 3 || p.Running() {
+		t.Errorf("pulled %d values, running %v, want 3, false", p.Pulled(), p.Running())
+	}
+}
+
+func TestMapIndexed(t *testing.T) {
+	in := []string{"a", "b", "c", "d"}
+	got := slices.Collect(MapIndexed(slices.Values(in), func(i int, s string) string {
+		return strconv.Itoa(i) + s
+	}))
+	if d := cmp.Diff(got, []string{"0a", "1b", "2c", "3d"}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+
+	// The index counts values from the source, not whatever makes it
+	// through later stages.
+	odd := func(s string) bool { return len(s) > 0 && (s[0]-'0')%2 == 1 }
+	got = slices.Collect(Filter(MapIndexed(slices.Values(in), func(i int, s string) string {
+		return strconv.Itoa(i) + s
+	}), odd))
+	if d := cmp.Diff(got, []string{"1b", "3d"}); d != "" {
+		t.Errorf("filtered mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestMapIndexedEarlyBreak(t *testing.T) {
+	var indices []int
+	for v := range MapIndexed(naturals(), func(i, v int) int {
+		indices = append(indices, i)
+		return v
+	}) {
+		if v == 2 {
+			break
+		}
+	}
+	if d := cmp.Diff(indices, []int{0, 1, 2}); d != "" {
+		t.Errorf("f called with (-got, +want):\n%v", d)
+	}
+}
+
+func TestFilterIndexed(t *testing.T) {
+	// A header, then samples.
+	rows := []string{"header", "s1", "s2", "s3", "s4", "s5", "s6", "s7"}
+	var indices, kept []int
+	got := slices.Collect(FilterIndexed(slices.Values(rows), func(i int, _ string) bool {
+		indices = append(indices, i)
+		keep := i != 0 && i%3 != 0
+		if keep {
+			kept = append(kept, i)
+		}
+		return keep
+	}))
+	if d := cmp.Diff(got, []string{"s1", "s2", "s4", "s5", "s7"}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	// p sees the position in the source, even after values have been
+	// dropped, so the kept values have their source positions rather
+	// than 0, 1, 2... for their positions in the output.
+	if d := cmp.Diff(indices, []int{0, 1, 2, 3, 4, 5, 6, 7}); d != "" {
+		t.Errorf("indices mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(kept, []int{1, 2, 4, 5, 7}); d != "" {
+		t.Errorf("kept indices mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestFilterIndexedEarlyBreak(t *testing.T) {
+	p := seqtest.NewProbe(naturals())
+	var got []int
+	for v := range FilterIndexed(p.Seq(), func(i, _ int) bool { return i%2 == 1 }) {
+		got = append(got, v)
+		if len(got) == 2 {
+			break
+		}
+	}
+	if d := cmp.Diff(got, []int{1, 3}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if p.Pulled() != 4 || p.Running() {
+		t.Errorf("pulled %d values, running %v, want 4, false", p.Pulled(), p.Running())
+	}
+}
+
+func TestMapKeysValues(t *testing.T) {
+	in := []Pair[string, int]{{"A", 1}, {"b", 2}, {"C", 3}}
+	for _, c := range []struct {
+		name string
+		f    func(iter.Seq2[string, int]) iter.Seq2[string, int]
+		want []Pair[string, int]
+	}{{
+		name: "MapKeys",
+		f: func(it iter.Seq2[string, int]) iter.Seq2[string, int] {
+			return MapKeys(it, strings.ToLower)
+		},
+		want: []Pair[string, int]{{"a", 1}, {"b", 2}, {"c", 3}},
+	}, {
+		name: "MapValues",
+		f: func(it iter.Seq2[string, int]) iter.Seq2[string, int] {
+			return MapValues(it, func(v int) int { return v * 10 })
+		},
+		want: []Pair[string, int]{{"A", 10}, {"b", 20}, {"C", 30}},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got := Collect2(c.f(Unpair(slices.Values(in))))
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			// Stopping early stops the source.
+			p := seqtest.NewProbe(slices.Values(in))
+			for range c.f(Unpair(p.Seq())) {
+				break
+			}
+			if p.Pulled() != 1 || p.Running() {
+				t.Errorf("pulled %d values, running %v, want 1, false", p.Pulled(), p.Running())
+			}
+		})
+	}
+}
+
+func TestMapKeysMap(t *testing.T) {
+	m := map[string]int{"One": 1, "TWO": 2, "three": 3}
+	got := CollectMap(MapKeys(maps.All(m), strings.ToLower))
+	if d := cmp.Diff(got, map[string]int{"one": 1, "two": 2, "three": 3}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestMapValuesTypes(t *testing.T) {
+	m := map[string]string{"a": "x", "b": "yy", "c": "zzz"}
+	lengths := CollectMap(MapValues(maps.All(m), func(s string) int { return len(s) }))
+	if d := cmp.Diff(lengths, map[string]int{"a": 1, "b": 2, "c": 3}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+
+	got := Collect2(MapValues(Enumerate(slices.Values([]int{4, 5})), strconv.Itoa))
+	if d := cmp.Diff(got, []Pair[int, string]{{0, "4"}, {1, "5"}}); d != "" {
+		t.Errorf("enumerate mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestFilter2(t *testing.T) {
+	in := []Pair[string, int]{{"a", 1}, {"b", 2}, {"c", 3}, {"a", 4}, {"d", 5}}
+	for _, c := range []struct {
+		name string
+		f    func(iter.Seq2[string, int]) iter.Seq2[string, int]
+		want []Pair[string, int]
+	}{{
+		name: "Filter2",
+		f: func(it iter.Seq2[string, int]) iter.Seq2[string, int] {
+			return Filter2(it, func(k string, v int) bool { return k == "a" || v == 3 })
+		},
+		want: []Pair[string, int]{{"a", 1}, {"c", 3}, {"a", 4}},
+	}, {
+		name: "FilterKeys",
+		f: func(it iter.Seq2[string, int]) iter.Seq2[string, int] {
+			return FilterKeys(it, func(k string) bool { return k != "a" })
+		},
+		want: []Pair[string, int]{{"b", 2}, {"c", 3}, {"d", 5}},
+	}, {
+		name: "FilterValues",
+		f: func(it iter.Seq2[string, int]) iter.Seq2[string, int] {
+			return FilterValues(it, func(v int) bool { return v%2 == 0 })
+		},
+		want: []Pair[string, int]{{"b", 2}, {"a", 4}},
+	}, {
+		name: "FilterKeysIn",
+		f: func(it iter.Seq2[string, int]) iter.Seq2[string, int] {
+			return FilterKeysIn(it, map[string]struct{}{"a": {}, "d": {}, "z": {}})
+		},
+		want: []Pair[string, int]{{"a", 1}, {"a", 4}, {"d", 5}},
+	}, {
+		name: "FilterKeysIn-empty",
+		f: func(it iter.Seq2[string, int]) iter.Seq2[string, int] {
+			return FilterKeysIn(it, nil)
+		},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got := Collect2(c.f(Unpair(slices.Values(in))))
+			if d := cmp.Diff(got, c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			// Stopping after the first match stops the source.
+			if len(c.want) == 0 {
+				return
+			}
+			p := seqtest.NewProbe(slices.Values(in))
+			for range c.f(Unpair(p.Seq())) {
+				break
+			}
+			first := slices.Index(in, c.want[0])
+			if p.Pulled() != first+1 || p.Running() {
+				t.Errorf("pulled %d values, running %v, want %d, false", p.Pulled(), p.Running(), first+1)
+			}
+		})
+	}
+}
+
+func TestUniqueKeys(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		in        []Pair[string, int]
+		wantFirst []Pair[string, int]
+		wantLast  []Pair[string, int]
+	}{{
+		name: "empty",
+	}, {
+		name:      "no-duplicates",
+		in:        []Pair[string, int]{{"a", 1}, {"b", 2}},
+		wantFirst: []Pair[string, int]{{"a", 1}, {"b", 2}},
+		wantLast:  []Pair[string, int]{{"a", 1}, {"b", 2}},
+	}, {
+		name:      "interleaved",
+		in:        []Pair[string, int]{{"a", 1}, {"b", 2}, {"a", 3}, {"c", 4}, {"b", 5}, {"a", 6}},
+		wantFirst: []Pair[string, int]{{"a", 1}, {"b", 2}, {"c", 4}},
+		wantLast:  []Pair[string, int]{{"a", 6}, {"b", 5}, {"c", 4}},
+	}, {
+		name:      "all-same",
+		in:        []Pair[string, int]{{"a", 1}, {"a", 2}, {"a", 3}},
+		wantFirst: []Pair[string, int]{{"a", 1}},
+		wantLast:  []Pair[string, int]{{"a", 3}},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			first := Collect2(UniqueKeysFirst(Unpair(slices.Values(c.in))))
+			if d := cmp.Diff(first, c.wantFirst, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("UniqueKeysFirst mismatch (-got, +want):\n%v", d)
+			}
+			last := Collect2(UniqueKeysLast(Unpair(slices.Values(c.in))))
+			if d := cmp.Diff(last, c.wantLast, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("UniqueKeysLast mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestUniqueKeysFirstStreams(t *testing.T) {
+	// Keys 0, 0, 1, 1, 2, 2...
+	p := seqtest.NewProbe(naturals())
+	pairs := Map1x2(p.Seq(), func(v int) (int, int) { return v / 2, v })
+	var got []Pair[int, int]
+	for k, v := range UniqueKeysFirst(pairs) {
+		got = append(got, NewPair(k, v))
+		if k == 2 {
+			break
+		}
+	}
+	if d := cmp.Diff(got, []Pair[int, int]{{0, 0}, {1, 2}, {2, 4}}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if p.Pulled() != 5 || p.Running() {
+		t.Errorf("pulled %d values, running %v, want 5, false", p.Pulled(), p.Running())
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		rows    [][]int
+		want    [][]int
+		wantErr bool
+	}{{
+		name: "empty",
+	}, {
+		name: "one-row",
+		rows: [][]int{{1, 2, 3}},
+		want: [][]int{{1}, {2}, {3}},
+	}, {
+		name: "one-column",
+		rows: [][]int{{1}, {2}, {3}},
+		want: [][]int{{1, 2, 3}},
+	}, {
+		name: "rectangle",
+		rows: [][]int{{1, 2, 3}, {4, 5, 6}},
+		want: [][]int{{1, 4}, {2, 5}, {3, 6}},
+	}, {
+		name: "empty-rows",
+		rows: [][]int{{}, {}},
+		want: [][]int{},
+	}, {
+		name:    "short-row",
+		rows:    [][]int{{1, 2, 3}, {4, 5}, {6, 7, 8}},
+		wantErr: true,
+	}, {
+		name:    "long-row",
+		rows:    [][]int{{1, 2}, {3, 4, 5}},
+		wantErr: true,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := Transpose(slices.Values(c.rows))
+			if (err != nil) != c.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, c.wantErr)
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestTransposeBatches(t *testing.T) {
+	// Batch reuses its slice, which mustn't matter.
+	got, err := Transpose(Batch(Limit(naturals(), 6), 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, [][]int{{0, 2, 4}, {1, 3, 5}}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestTransposeTwice(t *testing.T) {
+	r := rand.New(rand.NewPCG(11, 12))
+	for range 100 {
+		rows := make([][]int, 1+r.IntN(5))
+		width := 1 + r.IntN(5)
+		for i := range rows {
+			rows[i] = make([]int, width)
+			for j := range rows[i] {
+				rows[i][j] = r.Int()
+			}
+		}
+		cols, err := Transpose(slices.Values(rows))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Transpose(slices.Values(cols))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := cmp.Diff(got, rows); d != "" {
+			t.Fatalf("transposing twice mismatch (-got, +want):\n%v", d)
+		}
+	}
+}
+
+func TestPrependAppend(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		f    func(iter.Seq[int], ...int) iter.Seq[int]
+		in   []int
+		vs   []int
+		want []int
+	}{{
+		name: "prepend",
+		f:    Prepend[int],
+		in:   []int{3, 4},
+		vs:   []int{1, 2},
+		want: []int{1, 2, 3, 4},
+	}, {
+		name: "prepend-nothing",
+		f:    Prepend[int],
+		in:   []int{3, 4},
+		want: []int{3, 4},
+	}, {
+		name: "prepend-to-empty",
+		f:    Prepend[int],
+		vs:   []int{1, 2},
+		want: []int{1, 2},
+	}, {
+		name: "append",
+		f:    Append[int],
+		in:   []int{1, 2},
+		vs:   []int{3, 4},
+		want: []int{1, 2, 3, 4},
+	}, {
+		name: "append-nothing",
+		f:    Append[int],
+		in:   []int{1, 2},
+		want: []int{1, 2},
+	}, {
+		name: "append-to-empty",
+		f:    Append[int],
+		vs:   []int{3, 4},
+		want: []int{3, 4},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got := slices.Collect(c.f(slices.Values(c.in), c.vs...))
+			if d := cmp.Diff(got, c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestPrependEarlyBreak(t *testing.T) {
+	src, pulled, _ := countingSeq(3, 4, 5)
+	for v := range Prepend(src, 1, 2) {
+		if v == 2 {
+			break
+		}
+	}
+	if *pulled != 0 {
+		t.Errorf("pulled %d values from the source, want 0", *pulled)
+	}
+	for v := range Prepend(src, 1, 2) {
+		if v == 3 {
+			break
+		}
+	}
+	if *pulled != 1 {
+		t.Errorf("pulled %d values from the source, want 1", *pulled)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   [][]int
+		want []int
+		// The number of values pulled from each of in.
+		wantPulled []int
+	}{{
+		name: "none",
+	}, {
+		name:       "all-empty",
+		in:         [][]int{nil, nil, nil},
+		wantPulled: []int{0, 0, 0},
+	}, {
+		name:       "first",
+		in:         [][]int{{1, 2}, {3, 4}, {5}},
+		want:       []int{1, 2},
+		wantPulled: []int{2, 0, 0},
+	}, {
+		name:       "second",
+		in:         [][]int{nil, {3, 4}, {5}},
+		want:       []int{3, 4},
+		wantPulled: []int{0, 2, 0},
+	}, {
+		name:       "last",
+		in:         [][]int{nil, nil, {5}},
+		want:       []int{5},
+		wantPulled: []int{0, 0, 1},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				its     []iter.Seq[int]
+				pulled  []*int
+				started []*bool
+			)
+			for _, in := range c.in {
+				seq, p, _ := countingSeq(in...)
+				s := new(bool)
+				its = append(its, func(yield func(int) bool) {
+					*s = true
+					seq(yield)
+				})
+				pulled = append(pulled, p)
+				started = append(started, s)
+			}
+			got := slices.Collect(Coalesce(its...))
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			for i, p := range pulled {
+				if *p != c.wantPulled[i] {
+					t.Errorf("pulled %d values from iterator %d, want %d", *p, i, c.wantPulled[i])
+				}
+			}
+			// Everything after the chosen iterator should be left alone.
+			if i := slices.IndexFunc(c.wantPulled, func(n int) bool { return n > 0 }); i >= 0 {
+				for j, s := range started[i+1:] {
+					if *s {
+						t.Errorf("iterator %d was started", i+1+j)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCoalesceEarlyBreak(t *testing.T) {
+	empty, _, _ := countingSeq[int]()
+	src, pulled, returned := countingSeq(1, 2, 3)
+	rest, restPulled, _ := countingSeq(4)
+	for range Coalesce(empty, src, rest) {
+		break
+	}
+	if *pulled != 1 || !*returned {
+		t.Errorf("pulled %d values and returned: %v, want 1 and true", *pulled, *returned)
+	}
+	if *restPulled != 0 {
+		t.Errorf("pulled %d values from the last iterator", *restPulled)
+	}
+}
+
+func TestDefaultIfEmpty(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   []string
+		def  []string
+		want []string
+	}{{
+		name: "empty",
+		def:  []string{"-", "-"},
+		want: []string{"-", "-"},
+	}, {
+		name: "non-empty",
+		in:   []string{"a", "b"},
+		def:  []string{"-"},
+		want: []string{"a", "b"},
+	}, {
+		name: "no-default",
+		in:   []string{"a", "b"},
+		want: []string{"a", "b"},
+	}, {
+		name: "empty-no-default",
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			src, pulled, _ := countingSeq(c.in...)
+			calls := 0
+			counted := func(yield func(string) bool) {
+				calls++
+				src(yield)
+			}
+			got := slices.Collect(DefaultIfEmpty(counted, c.def...))
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if calls != 1 || *pulled != len(c.in) {
+				t.Errorf("ranged over the source %d times, pulling %d values, want once, pulling %d", calls, *pulled, len(c.in))
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package it
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// DecodeJSON returns an iterator that lazily decodes values of type T from r,
+// which can contain either a single JSON array of values or a stream of JSON
+// values, such as newline delimited JSON. If the first thing in r is a '[' it
+// is always treated as an array, so a stream of arrays needs to be wrapped in
+// an outer array. Any error, including trailing data after an array, is
+// yielded as the final element.
+func DecodeJSON[T any](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		br := bufio.NewReader(r)
+		first, err := peekNonSpace(br)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		dec := json.NewDecoder(br)
+		if first != '[' {
+			for {
+				var t T
+				err := dec.Decode(&t)
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					yield(zero, err)
+					return
+				}
+				if !yield(t, nil) {
+					return
+				}
+			}
+		}
+
+		if _, err := dec.Token(); err != nil {
+			yield(zero, err)
+			return
+		}
+		for dec.More() {
+			var t T
+			if err := dec.Decode(&t); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(t, nil) {
+				return
+			}
+		}
+		// The closing bracket, and then there should be nothing else.
+		if _, err := dec.Token(); err != nil {
+			yield(zero, err)
+			return
+		}
+		if tok, err := dec.Token(); err != io.EOF {
+			if err == nil {
+				err = fmt.Errorf("it.DecodeJSON: unexpected %v after array", tok)
+			}
+			yield(zero, err)
+		}
+	}
+}
+
+// peekNonSpace skips over any leading JSON whitespace and returns the next
+// byte, without consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
+
+// EncodeJSONArray writes the values of it to w as a single JSON array, one
+// element at a time, without buffering the whole array. It stops at and returns
+// the first error from encoding a value or writing to w, in which case w is
+// left holding an incomplete array.
+func EncodeJSONArray[T any](w io.Writer, it iter.Seq[T]) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	var (
+		buf   bytes.Buffer
+		enc   = json.NewEncoder(&buf)
+		first = true
+	)
+	for t := range it {
+		buf.Reset()
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		if err := enc.Encode(t); err != nil {
+			return err
+		}
+		// Drop the newline that Encode adds after every value.
+		buf.Truncate(buf.Len() - 1)
+		if _, err := buf.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+// EncodeNDJSON writes the values of it to w as newline delimited JSON, one value
+// per line. It stops at and returns the first error from encoding a value or
+// writing to w.
+func EncodeNDJSON[T any](w io.Writer, it iter.Seq[T]) error {
+	enc := json.NewEncoder(w)
+	for t := range it {
+		if err := enc.Encode(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package it
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+type record struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	records := []record{{"a", 1}, {"b", 2}, {"c", 3}}
+	for _, c := range []struct {
+		name    string
+		in      string
+		want    []record
+		wantErr bool
+	}{{
+		name: "empty",
+		in:   "",
+	}, {
+		name: "whitespace",
+		in:   " \n\t ",
+	}, {
+		name: "empty-array",
+		in:   " [ ] ",
+	}, {
+		name: "array",
+		in:   `[{"name": "a", "count": 1}, {"name": "b", "count": 2}, {"name": "c", "count": 3}]`,
+		want: records,
+	}, {
+		name: "array-with-whitespace",
+		in:   "\n  [\n{\"name\": \"a\", \"count\": 1},\n{\"name\": \"b\", \"count\": 2},\n{\"name\": \"c\", \"count\": 3}\n]\n",
+		want: records,
+	}, {
+		name: "ndjson",
+		in:   "{\"name\": \"a\", \"count\": 1}\n{\"name\": \"b\", \"count\": 2}\n{\"name\": \"c\", \"count\": 3}\n",
+		want: records,
+	}, {
+		name: "ndjson-no-trailing-newline",
+		in:   "{\"name\": \"a\", \"count\": 1}\n{\"name\": \"b\", \"count\": 2}\n{\"name\": \"c\", \"count\": 3}",
+		want: records,
+	}, {
+		name:    "array-trailing-garbage",
+		in:      `[{"name": "a", "count": 1}, {"name": "b", "count": 2}, {"name": "c", "count": 3}] {}`,
+		want:    records,
+		wantErr: true,
+	}, {
+		name:    "array-syntax-error",
+		in:      `[{"name": "a", "count": 1}, {"name": "b", "count": 2}, {"name": "c", "count": 3`,
+		want:    records[:2],
+		wantErr: true,
+	}, {
+		name:    "ndjson-syntax-error",
+		in:      "{\"name\": \"a\", \"count\": 1}\n{\"name\": \"b\", \"count\": 2}\n{\"name\": \"c\",",
+		want:    records[:2],
+		wantErr: true,
+	}, {
+		name:    "wrong-type",
+		in:      `[{"name": "a", "count": 1}, {"name": "b", "count": "two"}]`,
+		want:    records[:1],
+		wantErr: true,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := CollectErr(DecodeJSON[record](strings.NewReader(c.in)))
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if (err != nil) != c.wantErr {
+				t.Errorf("got error %v, want error: %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONReadError(t *testing.T) {
+	errRead := errors.New("read")
+	r := io.MultiReader(strings.NewReader(`[1, 2, `), iotest.ErrReader(errRead))
+	got, err := CollectErr(DecodeJSON[int](r))
+	if d := cmp.Diff(got, []int{1, 2}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if !errors.Is(err, errRead) {
+		t.Errorf("got error %v, want %v", err, errRead)
+	}
+}
+
+func TestDecodeJSONEarlyBreak(t *testing.T) {
+	values := make([]int, 100000)
+	for i := range values {
+		values[i] = i
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(data)
+	var got []int
+	for v, err := range DecodeJSON[int](r) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, v)
+		if len(got) == 10 {
+			break
+		}
+	}
+	if d := cmp.Diff(got, values[:10]); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	// Only a few buffers' worth should have been read.
+	if read := len(data) - r.Len(); read > len(data)/4 {
+		t.Errorf("read %d of %d bytes", read, len(data))
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	records := []record{{"a", 1}, {"b", 2}, {"c", 3}}
+	for _, c := range []struct {
+		name   string
+		encode func(io.Writer, []record) error
+		want   string
+	}{{
+		name: "array",
+		encode: func(w io.Writer, rs []record) error {
+			return EncodeJSONArray(w, slices.Values(rs))
+		},
+		want: `[{"name":"a","count":1},{"name":"b","count":2},{"name":"c","count":3}]`,
+	}, {
+		name: "ndjson",
+		encode: func(w io.Writer, rs []record) error {
+			return EncodeNDJSON(w, slices.Values(rs))
+		},
+		want: "{\"name\":\"a\",\"count\":1}\n{\"name\":\"b\",\"count\":2}\n{\"name\":\"c\",\"count\":3}\n",
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			for _, rs := range [][]record{nil, records[:1], records} {
+				var buf bytes.Buffer
+				if err := c.encode(&buf, rs); err != nil {
+					t.Fatalf("encode(%v): %v", rs, err)
+				}
+				got, err := CollectErr(DecodeJSON[record](&buf))
+				if err != nil {
+					t.Fatalf("decode(%v): %v", rs, err)
+				}
+				if d := cmp.Diff(got, rs, cmpopts.EquateEmpty()); d != "" {
+					t.Errorf("round trip mismatch (-got, +want):\n%v", d)
+				}
+			}
+			var buf bytes.Buffer
+			if err := c.encode(&buf, records); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != c.want {
+				t.Errorf("encoded %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestEncodeJSONErrors(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		encode func(io.Writer, iter.Seq[float64]) error
+	}{
+		{name: "array", encode: EncodeJSONArray[float64]},
+		{name: "ndjson", encode: EncodeNDJSON[float64]},
+	} {
+		t.Run(c.name+"/marshal", func(t *testing.T) {
+			seq, pulled, _ := countingSeq(1, 2, math.NaN(), 4, 5)
+			var buf bytes.Buffer
+			err := c.encode(&buf, seq)
+			var jsonErr *json.UnsupportedValueError
+			if !errors.As(err, &jsonErr) {
+				t.Errorf("got error %v, want a *json.UnsupportedValueError", err)
+			}
+			if *pulled != 3 {
+				t.Errorf("pulled %d values, want 3", *pulled)
+			}
+			if strings.Contains(buf.String(), "NaN") {
+				t.Errorf("wrote invalid value: %q", buf.String())
+			}
+		})
+		t.Run(c.name+"/write", func(t *testing.T) {
+			seq, pulled, _ := countingSeq(1.0, 2, 3, 4, 5)
+			w := &brokenWriter{n: 4}
+			if err := c.encode(w, seq); !errors.Is(err, errWrite) {
+				t.Errorf("got error %v, want %v", err, errWrite)
+			}
+			if *pulled > 3 {
+				t.Errorf("pulled %d values after the write failed", *pulled)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package it
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"slices"
+)
+
+// Number is a constraint that permits any integer or floating point type.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Delta returns an iterator over the differences between consecutive values of
+// it, so it yields one fewer value than it, and nothing if it yields fewer than
+// two. This turns a running total back into the values that were summed.
+func Delta[A Number](it iter.Seq[A]) iter.Seq[A] {
+	return DeltaFunc(it, func(prev, cur A) A { return cur - prev })
+}
+
+// DeltaFunc is like Delta, but uses f to find the difference between each
+// value of it and the one before it.
+func DeltaFunc[A, B any](it iter.Seq[A], f func(prev, cur A) B) iter.Seq[B] {
+	return func(yield func(B) bool) {
+		var (
+			prev  A
+			first = true
+		)
+		for cur := range it {
+			if first {
+				prev, first = cur, false
+				continue
+			}
+			if !yield(f(prev, cur)) {
+				return
+			}
+			prev = cur
+		}
+	}
+}
+
+// Summary holds summary statistics for a set of numbers, which can be updated
+// one number at a time. The zero value summarises no numbers.
+type Summary struct {
+	// Count is how many numbers have been added.
+	Count int
+	// Mean is their arithmetic mean.
+	Mean float64
+	// Min and Max are the smallest and largest.
+	Min, Max float64
+
+	// m2 is the sum of squared differences from the mean.
+	m2 float64
+}
+
+// Add updates s to include x, using Welford's algorithm so that the variance
+// stays accurate even when the numbers are large relative to their spread.
+func (s *Summary) Add(x float64) {
+	s.Count++
+	if s.Count == 1 {
+		s.Min, s.Max = x, x
+	}
+	s.Min, s.Max = min(s.Min, x), max(s.Max, x)
+	delta := x - s.Mean
+	s.Mean += delta / float64(s.Count)
+	s.m2 += delta * (x - s.Mean)
+}
+
+// Merge returns a summary of the numbers in both s and o.
+func (s Summary) Merge(o Summary) Summary {
+	switch {
+	case s.Count == 0:
+		return o
+	case o.Count == 0:
+		return s
+	}
+	n := float64(s.Count + o.Count)
+	delta := o.Mean - s.Mean
+	return Summary{
+		Count: s.Count + o.Count,
+		Mean:  s.Mean + delta*float64(o.Count)/n,
+		Min:   min(s.Min, o.Min),
+		Max:   max(s.Max, o.Max),
+		m2:    s.m2 + o.m2 + delta*delta*float64(s.Count)*float64(o.Count)/n,
+	}
+}
+
+// Variance returns the population variance of the numbers, or 0 if there are
+// none.
+func (s Summary) Variance() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.m2 / float64(s.Count)
+}
+
+// SampleVariance returns the unbiased sample variance of the numbers, or 0 if
+// there are fewer than two.
+func (s Summary) SampleVariance() float64 {
+	if s.Count < 2 {
+		return 0
+	}
+	return s.m2 / float64(s.Count-1)
+}
+
+// Running returns an iterator that yields each value of it along with a
+// Summary of all of the values up to and including it.
+func Running[A Number](it iter.Seq[A]) iter.Seq2[A, Summary] {
+	return func(yield func(A, Summary) bool) {
+		var s Summary
+		for a := range it {
+			s.Add(float64(a))
+			if !yield(a, s) {
+				return
+			}
+		}
+	}
+}
+
+// MovingAverage returns an iterator that yields, for each value of it, the mean
+// of that value and the up to n-1 values before it. The first n-1 averages are
+// over however many values there have been so far. The running total is kept
+// using compensated summation, so rounding errors don't build up over long
+// sequences. It panics if n is not positive.
+func MovingAverage[A Number](it iter.Seq[A], n int) iter.Seq[float64] {
+	if n <= 0 {
+		panic(fmt.Sprintf("it.MovingAverage: invalid window size %d", n))
+	}
+	return func(yield func(float64) bool) {
+		var (
+			window = make([]float64, 0, n)
+			next   = 0
+			sum    kahanSum
+		)
+		for a := range it {
+			x := float64(a)
+			if len(window) < n {
+				window = append(window, x)
+			} else {
+				sum.Add(-window[next])
+				window[next] = x
+				next = (next + 1) % n
+			}
+			sum.Add(x)
+			if !yield(sum.Sum() / float64(len(window))) {
+				return
+			}
+		}
+	}
+}
+
+// kahanSum is a running total that keeps track of the rounding error, using
+// Neumaier's variant of Kahan summation.
+type kahanSum struct {
+	sum, c float64
+}
+
+func (k *kahanSum) Add(x float64) {
+	t := k.sum + x
+	if math.Abs(k.sum) >= math.Abs(x) {
+		k.c += (k.sum - t) + x
+	} else {
+		k.c += (x - t) + k.sum
+	}
+	k.sum = t
+}
+
+func (k *kahanSum) Sum() float64 { return k.sum + k.c }
+
+// Quantiles collects the values of it and returns the value at each of the
+// quantiles qs, using the nearest-rank method: the q quantile of n sorted values
+// is the one at position ⌈q·n⌉, counting from one, or the smallest value if q is
+// zero. The results are always values that it yielded. The boolean is false if
+// it is empty. It panics if any of qs are outside [0, 1].
+func Quantiles[A Number](it iter.Seq[A], qs ...float64) ([]A, bool) {
+	for _, q := range qs {
+		if !(q >= 0 && q <= 1) {
+			panic(fmt.Sprintf("it.Quantiles: quantile %v out of range [0, 1]", q))
+		}
+	}
+	values := slices.Sorted(it)
+	if len(values) == 0 {
+		return nil, false
+	}
+	result := make([]A, len(qs))
+	for i, q := range qs {
+		result[i] = values[max(nearestRank(q, len(values)), 1)-1]
+	}
+	return result, true
+}
+
+// nearestRank returns ⌈q·n⌉. q·n is computed in floating point, so when it
+// should be a whole number it can come out a little above it, as with q = 0.28
+// and n = 25, and the ceiling would then be one too many. Anything within a few
+// ULPs of a whole number is treated as that number.
+func nearestRank(q float64, n int) int {
+	x := q * float64(n)
+	if r := math.Round(x); math.Abs(x-r) <= 4*(math.Nextafter(r, math.Inf(1))-r) {
+		return int(r)
+	}
+	return int(math.Ceil(x))
+}
@@ -0,0 +1,338 @@
+package it
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestDelta(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   []int
+		want []int
+	}{
+		{name: "empty"},
+		{name: "one", in: []int{5}},
+		{name: "two", in: []int{5, 8}, want: []int{3}},
+		{name: "several", in: []int{1, 3, 6, 6, 2}, want: []int{2, 3, 0, -4}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got := slices.Collect(Delta(slices.Values(c.in)))
+			if d := cmp.Diff(got, c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 100 {
+		values := make([]int64, r.IntN(20))
+		for i := range values {
+			values[i] = r.Int64N(2000) - 1000
+		}
+		// Prefix sums, starting from an arbitrary offset.
+		sums := []int64{r.Int64N(100)}
+		for _, v := range values {
+			sums = append(sums, sums[len(sums)-1]+v)
+		}
+		got := slices.Collect(Delta(slices.Values(sums)))
+		if d := cmp.Diff(got, values, cmpopts.EquateEmpty()); d != "" {
+			t.Fatalf("Delta of prefix sums of %v mismatch (-got, +want):\n%v", values, d)
+		}
+	}
+}
+
+func TestDeltaFunc(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{start, start.Add(time.Second), start.Add(time.Minute), start.Add(time.Hour)}
+	got := slices.Collect(DeltaFunc(slices.Values(times), func(prev, cur time.Time) time.Duration {
+		return cur.Sub(prev)
+	}))
+	want := []time.Duration{time.Second, time.Minute - time.Second, time.Hour - time.Minute}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestDeltaEarlyBreak(t *testing.T) {
+	src, pulled, _ := countingSeq(1, 2, 4, 8, 16)
+	for d := range Delta(src) {
+		if d == 2 {
+			break
+		}
+	}
+	if *pulled != 3 {
+		t.Errorf("pulled %d values, want 3", *pulled)
+	}
+}
+
+// batchSummary computes the mean and population variance of values with the
+// two pass algorithm.
+func batchSummary(values []float64) (mean, variance float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float64(len(values))
+}
+
+func TestRunning(t *testing.T) {
+	values := []int{4, 7, 13, 16}
+	type snapshot struct {
+		Value          int
+		Count          int
+		Mean, Min, Max float64
+		Variance       float64
+	}
+	var got []snapshot
+	for v, s := range Running(slices.Values(values)) {
+		got = append(got, snapshot{v, s.Count, s.Mean, s.Min, s.Max, s.Variance()})
+	}
+	want := []snapshot{
+		{4, 1, 4, 4, 4, 0},
+		{7, 2, 5.5, 4, 7, 2.25},
+		{13, 3, 8, 4, 13, 14},
+		{16, 4, 10, 4, 16, 22.5},
+	}
+	if d := cmp.Diff(got, want, cmpopts.EquateApprox(0, 1e-12)); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestRunningEmpty(t *testing.T) {
+	for range Running(slices.Values([]float64(nil))) {
+		t.Error("yielded a value for empty input")
+	}
+	var s Summary
+	if s.Variance() != 0 || s.SampleVariance() != 0 {
+		t.Errorf("zero Summary has variance %v, sample variance %v", s.Variance(), s.SampleVariance())
+	}
+}
+
+func TestRunningStability(t *testing.T) {
+	// A large offset with a small spread is where the naive sum of squares
+	// approach falls apart.
+	r := rand.New(rand.NewPCG(3, 4))
+	values := make([]float64, 100000)
+	for i := range values {
+		values[i] = 1e9 + r.Float64()
+	}
+	var last Summary
+	for _, s := range Running(slices.Values(values)) {
+		last = s
+	}
+	mean, variance := batchSummary(values)
+	if d := cmp.Diff(last.Mean, mean, cmpopts.EquateApprox(1e-12, 0)); d != "" {
+		t.Errorf("mean mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(last.Variance(), variance, cmpopts.EquateApprox(1e-6, 0)); d != "" {
+		t.Errorf("variance mismatch (-got, +want):\n%v", d)
+	}
+	if got, want := last.SampleVariance(), variance*float64(len(values))/float64(len(values)-1); !cmp.Equal(got, want, cmpopts.EquateApprox(1e-6, 0)) {
+		t.Errorf("sample variance %v, want %v", got, want)
+	}
+}
+
+func TestSummaryMerge(t *testing.T) {
+	r := rand.New(rand.NewPCG(5, 6))
+	values := make([]float64, 1000)
+	for i := range values {
+		values[i] = r.NormFloat64()*10 + 100
+	}
+	var whole Summary
+	for _, v := range values {
+		whole.Add(v)
+	}
+	for _, split := range []int{0, 1, 500, 999, 1000} {
+		var a, b Summary
+		for _, v := range values[:split] {
+			a.Add(v)
+		}
+		for _, v := range values[split:] {
+			b.Add(v)
+		}
+		got := a.Merge(b)
+		opts := []cmp.Option{cmp.AllowUnexported(Summary{}), cmpopts.EquateApprox(1e-12, 0)}
+		if d := cmp.Diff(got, whole, opts...); d != "" {
+			t.Errorf("split at %d mismatch (-got, +want):\n%v", split, d)
+		}
+	}
+}
+
+func TestMovingAverage(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   []int
+		n    int
+		want []float64
+	}{
+		{name: "empty", n: 3},
+		{name: "window-1", in: []int{1, 2, 3}, n: 1, want: []float64{1, 2, 3}},
+		{name: "partial", in: []int{3, 6}, n: 3, want: []float64{3, 4.5}},
+		{name: "window-3", in: []int{3, 6, 9, 0, 3, 3}, n: 3, want: []float64{3, 4.5, 6, 5, 4, 2}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got := slices.Collect(MovingAverage(slices.Values(c.in), c.n))
+			if d := cmp.Diff(got, c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestMovingAverageAccuracy(t *testing.T) {
+	// Mostly small values with occasional huge spikes, so that naively
+	// adding and subtracting from a running total leaves behind the
+	// rounding error from each spike.
+	const n = 10
+	r := rand.New(rand.NewPCG(9, 10))
+	values := make([]float64, 100000)
+	for i := range values {
+		values[i] = r.Float64()
+		if r.IntN(100) == 0 {
+			values[i] *= math.Pow(10, float64(r.IntN(12)))
+		}
+	}
+	i := 0
+	for got := range MovingAverage(slices.Values(values), n) {
+		window := values[max(0, i-n+1) : i+1]
+		var want float64
+		for _, v := range slices.Sorted(slices.Values(window)) {
+			want += v
+		}
+		want /= float64(len(window))
+		if !cmp.Equal(got, want, cmpopts.EquateApprox(1e-9, 0)) {
+			t.Fatalf("average %d = %v, want %v", i, got, want)
+		}
+		i++
+	}
+}
+
+func TestMovingAveragePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MovingAverage(it, 0) didn't panic")
+		}
+	}()
+	MovingAverage(slices.Values([]int{1}), 0)
+}
+
+func TestQuantiles(t *testing.T) {
+	// Out of order, so that they have to be sorted.
+	ten := []int{70, 20, 100, 40, 10, 90, 30, 60, 50, 80}
+	for _, c := range []struct {
+		name string
+		in   []int
+		qs   []float64
+		want []int
+	}{{
+		name: "extremes",
+		in:   ten,
+		qs:   []float64{0, 1},
+		want: []int{10, 100},
+	}, {
+		name: "exact-ranks",
+		in:   ten,
+		qs:   []float64{0.1, 0.5, 0.9},
+		want: []int{10, 50, 90},
+	}, {
+		name: "round-up",
+		in:   ten,
+		// Ranks 0.5, 2.5, 9.9 and 9.99 round up to 1, 3, 10 and 10.
+		qs:   []float64{0.05, 0.25, 0.99, 0.999},
+		want: []int{10, 30, 100, 100},
+	}, {
+		name: "odd-length",
+		in:   []int{5, 1, 3, 2, 4},
+		// Ranks 2.5, 0.05 and 4.75.
+		qs:   []float64{0.5, 0.01, 0.95},
+		want: []int{3, 1, 5},
+	}, {
+		name: "single",
+		in:   []int{7},
+		qs:   []float64{0, 0.5, 1},
+		want: []int{7, 7, 7},
+	}, {
+		name: "duplicates",
+		in:   []int{1, 1, 1, 2},
+		qs:   []float64{0.75, 0.76},
+		want: []int{1, 2},
+	}, {
+		name: "no-quantiles",
+		in:   ten,
+		want: []int{},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := Quantiles(slices.Values(c.in), c.qs...)
+			if !ok {
+				t.Fatal("Quantiles returned !ok")
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestQuantilesWholeRanks(t *testing.T) {
+	// q·n should be a whole number k in all of these, so the quantile is
+	// the kth value, but the floating point product may not be exact.
+	for n := 1; n <= 200; n++ {
+		values := make([]int, n)
+		for i := range values {
+			values[i] = i + 1
+		}
+		for k := 0; k <= n; k++ {
+			q := float64(k) / float64(n)
+			got, _ := Quantiles(slices.Values(values), q)
+			if want := max(k, 1); got[0] != want {
+				t.Fatalf("Quantiles(1..%d, %v) = %d, want %d", n, q, got[0], want)
+			}
+		}
+	}
+}
+
+func TestQuantilesRounding(t *testing.T) {
+	values := make([]int, 25)
+	for i := range values {
+		values[i] = i + 1
+	}
+	// 0.28 * 25 is 7.000000000000001 in floating point.
+	if got, _ := Quantiles(slices.Values(values), 0.28); got[0] != 7 {
+		t.Errorf("Quantiles(1..25, 0.28) = %d, want 7", got[0])
+	}
+	// Not whole numbers, so they still round up.
+	if got, _ := Quantiles(slices.Values(values), 0.281, 0.279); got[0] != 8 || got[1] != 7 {
+		t.Errorf("Quantiles(1..25, 0.281, 0.279) = %v, want [8 7]", got)
+	}
+}
+
+func TestQuantilesEmpty(t *testing.T) {
+	if got, ok := Quantiles(slices.Values([]float64(nil)), 0.5); ok || got != nil {
+		t.Errorf("Quantiles(empty) = %v, %v, want nil, false", got, ok)
+	}
+}
+
+func TestQuantilesPanics(t *testing.T) {
+	for _, q := range []float64{-0.1, 1.1, math.NaN(), math.Inf(1)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Quantiles(_, %v) didn't panic", q)
+				}
+			}()
+			Quantiles(slices.Values([]float64{1, 2, 3}), 0.5, q)
+		}()
+	}
+}
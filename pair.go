@@ -0,0 +1,57 @@
+package it
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+)
+
+// String formats the pair as "(a, b)", formatting each value with %v.
+func (p Pair[A, B]) String() string {
+	return fmt.Sprintf("(%v, %v)", p.A, p.B)
+}
+
+// MarshalJSON encodes the pair as a JSON array of its two values.
+func (p Pair[A, B]) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]any{p.A, p.B})
+}
+
+// UnmarshalJSON decodes a pair from a JSON array of exactly two values.
+func (p *Pair[A, B]) UnmarshalJSON(data []byte) error {
+	var values []json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	if len(values) != 2 {
+		return fmt.Errorf("it.Pair: expected a JSON array of 2 values, got %d", len(values))
+	}
+	var q Pair[A, B]
+	if err := json.Unmarshal(values[0], &q.A); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(values[1], &q.B); err != nil {
+		return err
+	}
+	*p = q
+	return nil
+}
+
+// ComparePairs compares two pairs by their first values, and then by their
+// second values if the first are equal. It can be used with slices.SortFunc.
+func ComparePairs[A, B cmp.Ordered](x, y Pair[A, B]) int {
+	if c := cmp.Compare(x.A, y.A); c != 0 {
+		return c
+	}
+	return cmp.Compare(x.B, y.B)
+}
+
+// PairBy returns a function that compares pairs by the key that key returns
+// for their values, for use with slices.SortFunc and friends. For example,
+// to sort the output of Collect2 by the second value of each pair:
+//
+//	slices.SortFunc(pairs, PairBy(func(_ string, n int) int { return n }))
+func PairBy[A, B any, K cmp.Ordered](key func(A, B) K) func(x, y Pair[A, B]) int {
+	return func(x, y Pair[A, B]) int {
+		return cmp.Compare(key(x.A, x.B), key(y.A, y.B))
+	}
+}
@@ -0,0 +1,109 @@
+package it
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPairString(t *testing.T) {
+	for _, c := range []struct {
+		p    fmt.Stringer
+		want string
+	}{
+		{NewPair(1, "one"), "(1, one)"},
+		{NewPair("", 0.5), "(, 0.5)"},
+		{NewPair(NewPair(1, 2), []int{3, 4}), "((1, 2), [3 4])"},
+	} {
+		if got := c.p.String(); got != c.want {
+			t.Errorf("String() = %q, want %q", got, c.want)
+		}
+		if got := fmt.Sprint(c.p); got != c.want {
+			t.Errorf("Sprint() = %q, want %q", got, c.want)
+		}
+	}
+}
+
+// roundTrip checks that p encodes to want and decodes back to p.
+func roundTrip[A, B any](t *testing.T, p Pair[A, B], want string) {
+	t.Helper()
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal(%v): %v", p, err)
+	}
+	if string(data) != want {
+		t.Errorf("Marshal(%v) = %s, want %s", p, data, want)
+	}
+	var got Pair[A, B]
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal(%s): %v", data, err)
+	}
+	if d := cmp.Diff(got, p); d != "" {
+		t.Errorf("round trip mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestPairJSON(t *testing.T) {
+	roundTrip(t, NewPair(1, "one"), `[1,"one"]`)
+	roundTrip(t, NewPair("", []int(nil)), `["",null]`)
+	roundTrip(t, NewPair(NewPair(1, 2), NewPair("a", NewPair(true, 0.5))), `[[1,2],["a",[true,0.5]]]`)
+	roundTrip(t, NewPair(record{"a", 1}, map[string]int{"b": 2}), `[{"name":"a","count":1},{"b":2}]`)
+
+	// Pairs inside other things.
+	pairs := []Pair[string, int]{{"a", 1}, {"b", 2}}
+	data, err := json.Marshal(map[string][]Pair[string, int]{"pairs": pairs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"pairs":[["a",1],["b",2]]}`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+	var got map[string][]Pair[string, int]
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got["pairs"], pairs); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestPairUnmarshalErrors(t *testing.T) {
+	for _, in := range []string{
+		`[]`,
+		`[1]`,
+		`[1, "a", 2]`,
+		`{"A": 1, "B": "a"}`,
+		`["a", "a"]`,
+		`[1, 2]`,
+		`null`,
+	} {
+		var p Pair[int, string]
+		if err := json.Unmarshal([]byte(in), &p); err == nil {
+			t.Errorf("Unmarshal(%s) = %v, want error", in, p)
+		}
+	}
+}
+
+func TestComparePairs(t *testing.T) {
+	pairs := []Pair[string, int]{{"b", 1}, {"a", 2}, {"b", 0}, {"a", 1}}
+	slices.SortFunc(pairs, ComparePairs)
+	want := []Pair[string, int]{{"a", 1}, {"a", 2}, {"b", 0}, {"b", 1}}
+	if d := cmp.Diff(pairs, want); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestPairBy(t *testing.T) {
+	pairs := []Pair[string, int]{{"c", 1}, {"a", 3}, {"b", 2}}
+	slices.SortFunc(pairs, PairBy(func(_ string, n int) int { return n }))
+	if d := cmp.Diff(pairs, []Pair[string, int]{{"c", 1}, {"b", 2}, {"a", 3}}); d != "" {
+		t.Errorf("by value mismatch (-got, +want):\n%v", d)
+	}
+	slices.SortFunc(pairs, PairBy(func(s string, _ int) string { return s }))
+	if d := cmp.Diff(pairs, []Pair[string, int]{{"a", 3}, {"b", 2}, {"c", 1}}); d != "" {
+		t.Errorf("by key mismatch (-got, +want):\n%v", d)
+	}
+}
@@ -0,0 +1,263 @@
+package it
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"runtime"
+	"sync"
+)
+
+// ParallelMap is like Map, but applies f to up to workers items concurrently.
+// The results are still yielded in the same order as the input. If workers is
+// zero or negative it defaults to runtime.GOMAXPROCS(0).
+//
+// The input is ranged over in a separate goroutine. If the consumer stops
+// early no new work is started, and the iterator waits for any calls to f that
+// are already running to finish before returning, discarding their results.
+func ParallelMap[A, B any](it iter.Seq[A], workers int, f func(A) B) iter.Seq[B] {
+	workers = defaultWorkers(workers)
+	return func(yield func(B) bool) {
+		type job struct {
+			a   A
+			out chan B
+		}
+		var (
+			jobs = make(chan job)
+			// pending holds a slot for each result, in input
+			// order, which also bounds how far ahead of the
+			// consumer the workers can get.
+			pending = make(chan chan B, workers)
+			done    = make(chan struct{})
+			wg      sync.WaitGroup
+		)
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range jobs {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					j.out <- f(j.a)
+				}
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(jobs)
+			defer close(pending)
+			for a := range it {
+				out := make(chan B, 1)
+				select {
+				case pending <- out:
+				case <-done:
+					return
+				}
+				select {
+				case jobs <- job{a: a, out: out}:
+				case <-done:
+					return
+				}
+			}
+		}()
+		defer func() {
+			close(done)
+			if testHookStopped != nil {
+				testHookStopped()
+			}
+			wg.Wait()
+		}()
+
+		for out := range pending {
+			if !yield(<-out) {
+				return
+			}
+		}
+	}
+}
+
+// ParallelMapUnordered is like ParallelMap, but yields the results as soon as
+// they are ready rather than in input order. Each element of the input is
+// passed to f at most once. The same rules about early termination apply.
+func ParallelMapUnordered[A, B any](it iter.Seq[A], workers int, f func(A) B) iter.Seq[B] {
+	workers = defaultWorkers(workers)
+	return func(yield func(B) bool) {
+		var (
+			jobs    = make(chan A)
+			results = make(chan B)
+			done    = make(chan struct{})
+			wg      sync.WaitGroup
+		)
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for a := range jobs {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					select {
+					case results <- f(a):
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(jobs)
+			for a := range it {
+				select {
+				case jobs <- a:
+				case <-done:
+					return
+				}
+			}
+		}()
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+		defer func() {
+			close(done)
+			if testHookStopped != nil {
+				testHookStopped()
+			}
+			for range results {
+			}
+		}()
+
+		for b := range results {
+			if !yield(b) {
+				return
+			}
+		}
+	}
+}
+
+// ParallelMapUnorderedErr is ParallelMapUnordered for a fallible f. Every
+// result is yielded along with its error, failures don't stop the other
+// elements from being processed.
+func ParallelMapUnorderedErr[A, B any](it iter.Seq[A], workers int, f func(A) (B, error)) iter.Seq2[B, error] {
+	return Unpair(ParallelMapUnordered(it, workers, func(a A) Pair[B, error] {
+		return NewPair(f(a))
+	}))
+}
+
+// ParallelFilter is like Filter, but evaluates p on up to workers items
+// concurrently. The surviving items are yielded in their original order, and p
+// is called at most once per item. The same rules about early termination as
+// ParallelMap apply.
+func ParallelFilter[A any](it iter.Seq[A], workers int, p func(A) bool) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		tested := ParallelMap(it, workers, func(a A) Pair[A, bool] {
+			return NewPair(a, p(a))
+		})
+		for t := range tested {
+			if !t.B {
+				continue
+			}
+			if !yield(t.A) {
+				return
+			}
+		}
+	}
+}
+
+// ParallelForEach calls f for every item in it, running up to workers calls
+// concurrently. If workers is zero or negative it defaults to
+// runtime.GOMAXPROCS(0). The context passed to f is cancelled as soon as any
+// call returns an error, at which point no new calls are started and that
+// first error is returned. If ctx is cancelled, no new calls are started and
+// ctx's error is returned. In every case ParallelForEach waits for calls that
+// are already running to finish before returning.
+func ParallelForEach[A any](ctx context.Context, it iter.Seq[A], workers int, f func(context.Context, A) error) error {
+	return ParallelForEachOpts(ctx, it, ForEachOptions{Workers: workers}, f)
+}
+
+// ForEachOptions configures ParallelForEachOpts.
+type ForEachOptions struct {
+	// Workers is the maximum number of concurrent calls. If it is zero or
+	// negative it defaults to runtime.GOMAXPROCS(0).
+	Workers int
+	// ContinueOnError keeps processing items after a call returns an
+	// error, rather than cancelling everything. All of the errors are
+	// returned, joined with errors.Join.
+	ContinueOnError bool
+}
+
+// ParallelForEachOpts is ParallelForEach with more options.
+func ParallelForEachOpts[A any](ctx context.Context, it iter.Seq[A], opts ForEachOptions, f func(context.Context, A) error) error {
+	workers := defaultWorkers(opts.Workers)
+	fctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		jobs = make(chan A)
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range jobs {
+				if fctx.Err() != nil {
+					// Drain anything the dispatcher
+					// managed to send after cancellation.
+					continue
+				}
+				err := f(fctx, a)
+				if err == nil {
+					continue
+				}
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				if !opts.ContinueOnError {
+					cancel()
+				}
+			}
+		}()
+	}
+dispatch:
+	for a := range it {
+		select {
+		case jobs <- a:
+		case <-fctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
+	}
+	if !opts.ContinueOnError && len(errs) > 0 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// testHookStopped, if set, is called by ParallelMap and ParallelMapUnordered
+// once they have told their goroutines to stop, before waiting for them.
+var testHookStopped func()
+
+// defaultWorkers returns workers, or runtime.GOMAXPROCS(0) if it isn't
+// positive.
+func defaultWorkers(workers int) int {
+	if workers > 0 {
+		return workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
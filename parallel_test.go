@@ -0,0 +1,379 @@
+package it
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// jitter sleeps for a random short time.
+func jitter() {
+	time.Sleep(time.Duration(rand.IntN(200)) * time.Microsecond)
+}
+
+func TestParallelMap(t *testing.T) {
+	values := make([]int, 200)
+	for i := range values {
+		values[i] = i
+	}
+	want := slices.Collect(Map(slices.Values(values), strconv.Itoa))
+	for _, workers := range []int{-1, 0, 1, 2, 8, 500} {
+		t.Run(strconv.Itoa(workers), func(t *testing.T) {
+			defer checkGoroutines(t)()
+			got := slices.Collect(ParallelMap(slices.Values(values), workers, func(i int) string {
+				jitter()
+				return strconv.Itoa(i)
+			}))
+			if d := cmp.Diff(got, want); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestParallelMapEarlyBreak(t *testing.T) {
+	const workers = 4
+	defer checkGoroutines(t)()
+	var (
+		running atomic.Int32
+		calls   atomic.Int32
+	)
+	got := slices.Collect(Limit(ParallelMap(naturals(), workers, func(i int) int {
+		running.Add(1)
+		defer running.Add(-1)
+		calls.Add(1)
+		jitter()
+		return i * 2
+	}), 10))
+	if d := cmp.Diff(got, []int{0, 2, 4, 6, 8, 10, 12, 14, 16, 18}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if r := running.Load(); r != 0 {
+		t.Fatalf("%d calls still running after iterator returned", r)
+	}
+	// Work can only get so far ahead of the consumer: one slot per
+	// worker, plus one running in each worker, plus the one the
+	// dispatcher is waiting to hand out.
+	if c := calls.Load(); c > 10+2*workers+1 {
+		t.Fatalf("f called %d times", c)
+	}
+}
+
+// checkNoWorkAfterBreak checks that a parallel map built by parallelMap never
+// calls f for a value that is handed to a worker after the consumer stops.
+// Since the workers are idle at that point, dispatching a value races with
+// noticing that the consumer has gone, so it tries a few times.
+func checkNoWorkAfterBreak(t *testing.T, parallelMap func(iter.Seq[int], int, func(int) int) iter.Seq[int]) {
+	t.Helper()
+	defer checkGoroutines(t)()
+	defer func() { testHookStopped = nil }()
+	for range 20 {
+		var (
+			gate = make(chan struct{})
+			late atomic.Int32
+		)
+		// Two values straight away, the rest only once the iterator
+		// has told its workers to stop.
+		testHookStopped = func() { close(gate) }
+		src := func(yield func(int) bool) {
+			for i := 0; ; i++ {
+				if i == 2 {
+					<-gate
+				}
+				if !yield(i) {
+					return
+				}
+			}
+		}
+		for range parallelMap(src, 2, func(i int) int {
+			if i >= 2 {
+				late.Add(1)
+			}
+			return i
+		}) {
+			break
+		}
+		if n := late.Load(); n > 0 {
+			t.Fatalf("f called %d times after the consumer stopped", n)
+		}
+	}
+}
+
+func TestParallelMapNoWorkAfterBreak(t *testing.T) {
+	checkNoWorkAfterBreak(t, ParallelMap[int, int])
+}
+
+func BenchmarkParallelMap(b *testing.B) {
+	values := make([][]byte, 100)
+	for i := range values {
+		values[i] = []byte(strconv.Itoa(i))
+	}
+	// Something reasonably expensive.
+	hash := func(b []byte) [32]byte {
+		sum := sha256.Sum256(b)
+		for range 1000 {
+			sum = sha256.Sum256(sum[:])
+		}
+		return sum
+	}
+	b.Run("Map", func(b *testing.B) {
+		for b.Loop() {
+			for range Map(slices.Values(values), hash) {
+			}
+		}
+	})
+	b.Run("ParallelMap", func(b *testing.B) {
+		for b.Loop() {
+			for range ParallelMap(slices.Values(values), 0, hash) {
+			}
+		}
+	})
+}
+
+func TestParallelMapUnordered(t *testing.T) {
+	values := make([]int, 200)
+	for i := range values {
+		values[i] = i
+	}
+	want := slices.Sorted(Map(slices.Values(values), strconv.Itoa))
+	for _, workers := range []int{-1, 0, 1, 2, 8, 500} {
+		t.Run(strconv.Itoa(workers), func(t *testing.T) {
+			defer checkGoroutines(t)()
+			var calls sync.Map
+			got := slices.Sorted(ParallelMapUnordered(slices.Values(values), workers, func(i int) string {
+				if _, loaded := calls.LoadOrStore(i, true); loaded {
+					t.Errorf("f called twice with %d", i)
+				}
+				jitter()
+				return strconv.Itoa(i)
+			}))
+			if d := cmp.Diff(got, want); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestParallelMapUnorderedEarlyBreak(t *testing.T) {
+	const workers = 4
+	defer checkGoroutines(t)()
+	var (
+		running atomic.Int32
+		calls   atomic.Int32
+	)
+	got := slices.Collect(Limit(ParallelMapUnordered(naturals(), workers, func(i int) int {
+		running.Add(1)
+		defer running.Add(-1)
+		calls.Add(1)
+		jitter()
+		return i
+	}), 10))
+	if len(got) != 10 {
+		t.Fatalf("got %d values, want 10", len(got))
+	}
+	if r := running.Load(); r != 0 {
+		t.Fatalf("%d calls still running after iterator returned", r)
+	}
+	if c := calls.Load(); c > 10+workers {
+		t.Fatalf("f called %d times", c)
+	}
+}
+
+func TestParallelMapUnorderedNoWorkAfterBreak(t *testing.T) {
+	checkNoWorkAfterBreak(t, ParallelMapUnordered[int, int])
+}
+
+func TestParallelMapUnorderedErr(t *testing.T) {
+	defer checkGoroutines(t)()
+	in := []string{"1", "2", "x", "4", "y", "6"}
+	values, errs := PartitionErr(ParallelMapUnorderedErr(slices.Values(in), 3, strconv.Atoi))
+	slices.Sort(values)
+	if d := cmp.Diff(values, []int{1, 2, 4, 6}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("got errors %v, want 2", errs)
+	}
+}
+
+func TestParallelFilter(t *testing.T) {
+	values := make([]int, 200)
+	for i := range values {
+		values[i] = i
+	}
+	odd := func(i int) bool { return i%2 == 1 }
+	want := slices.Collect(Filter(slices.Values(values), odd))
+	for _, workers := range []int{0, 1, 2, 8, 500} {
+		t.Run(strconv.Itoa(workers), func(t *testing.T) {
+			defer checkGoroutines(t)()
+			var calls sync.Map
+			got := slices.Collect(ParallelFilter(slices.Values(values), workers, func(i int) bool {
+				if _, loaded := calls.LoadOrStore(i, true); loaded {
+					t.Errorf("p called twice with %d", i)
+				}
+				jitter()
+				return odd(i)
+			}))
+			if d := cmp.Diff(got, want); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestParallelFilterEarlyBreak(t *testing.T) {
+	defer checkGoroutines(t)()
+	got := slices.Collect(Limit(ParallelFilter(naturals(), 4, func(i int) bool {
+		jitter()
+		return i%3 == 0
+	}), 5))
+	if d := cmp.Diff(got, []int{0, 3, 6, 9, 12}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func BenchmarkParallelFilter(b *testing.B) {
+	values := make([]int, 50)
+	for i := range values {
+		values[i] = i
+	}
+	// Simulates waiting on an external service.
+	slowOdd := func(i int) bool {
+		time.Sleep(time.Millisecond)
+		return i%2 == 1
+	}
+	b.Run("Filter", func(b *testing.B) {
+		for b.Loop() {
+			for range Filter(slices.Values(values), slowOdd) {
+			}
+		}
+	})
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run("ParallelFilter-"+strconv.Itoa(workers), func(b *testing.B) {
+			for b.Loop() {
+				for range ParallelFilter(slices.Values(values), workers, slowOdd) {
+				}
+			}
+		})
+	}
+}
+
+func TestParallelForEach(t *testing.T) {
+	defer checkGoroutines(t)()
+	var (
+		mu   sync.Mutex
+		seen []int
+	)
+	err := ParallelForEach(t.Context(), Limit(naturals(), 100), 4, func(_ context.Context, i int) error {
+		jitter()
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, i)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slices.Sort(seen)
+	if d := cmp.Diff(seen, slices.Collect(Limit(naturals(), 100))); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestParallelForEachFirstError(t *testing.T) {
+	const workers = 4
+	defer checkGoroutines(t)()
+	var (
+		calls     atomic.Int32
+		late      atomic.Int32
+		cancelled atomic.Int32
+	)
+	err := ParallelForEach(t.Context(), naturals(), workers, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		if i == 10 {
+			return errA
+		}
+		if i > 10 {
+			late.Add(1)
+			// Wait for the failure to be noticed.
+			<-ctx.Done()
+			cancelled.Add(1)
+		}
+		return nil
+	})
+	if err != errA {
+		t.Fatalf("got error %v, want %v", err, errA)
+	}
+	// Source is infinite, so stopping at all shows dispatching stopped,
+	// but it shouldn't have got much further than the failure.
+	if c := calls.Load(); c > 10+workers {
+		t.Fatalf("f called %d times", c)
+	}
+	if c, l := cancelled.Load(), late.Load(); c != l {
+		t.Fatalf("%d calls saw cancellation, want %d", c, l)
+	}
+}
+
+func TestParallelForEachContinueOnError(t *testing.T) {
+	defer checkGoroutines(t)()
+	var calls atomic.Int32
+	err := ParallelForEachOpts(t.Context(), Limit(naturals(), 50), ForEachOptions{
+		Workers:         4,
+		ContinueOnError: true,
+	}, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		switch i {
+		case 10:
+			return errA
+		case 20:
+			return errB
+		case 49:
+			return errC
+		}
+		return nil
+	})
+	if c := calls.Load(); c != 50 {
+		t.Fatalf("f called %d times, want 50", c)
+	}
+	for _, want := range []error{errA, errB, errC} {
+		if !errors.Is(err, want) {
+			t.Errorf("got error %v, want one including %v", err, want)
+		}
+	}
+}
+
+func TestParallelForEachCancel(t *testing.T) {
+	for _, continueOnError := range []bool{false, true} {
+		t.Run(strconv.FormatBool(continueOnError), func(t *testing.T) {
+			defer checkGoroutines(t)()
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			var calls atomic.Int32
+			err := ParallelForEachOpts(ctx, naturals(), ForEachOptions{
+				Workers:         2,
+				ContinueOnError: continueOnError,
+			}, func(ctx context.Context, i int) error {
+				if calls.Add(1) == 5 {
+					cancel()
+				}
+				return nil
+			})
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("got error %v, want %v", err, context.Canceled)
+			}
+			if c := calls.Load(); c > 5+2+1 {
+				t.Fatalf("f called %d times", c)
+			}
+		})
+	}
+}
@@ -1,6 +1,13 @@
 package it
 
-import "iter"
+import (
+	"fmt"
+	"iter"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+)
 
 // Perm returns an iterator that yields all permutations of the provided slice.
 // It shuffles the objects in place, and always yields the same slice, so care
@@ -54,34 +61,206 @@ func permIter[E any, S ~[]E](data S) iter.Seq[S] {
 			return
 		}
 		ret := make(S, len(data))
-		yieldCopy := func() bool {
+		heapPerms(data, func() bool {
 			copy(ret, data)
 			return yield(ret)
+		})
+	}
+}
+
+// PermInPlace is like Perm, but yields data itself as it is permuted, rather
+// than copying it first. This is quicker when there are few elements and the
+// consumer does little with each permutation.
+//
+// The yielded slice is the working state of the iterator: its contents are
+// only valid until the next value is yielded, and it must not be modified.
+// Modifying it breaks the iteration, and the permutations yielded afterwards
+// are not well defined. Clone it to keep it.
+func PermInPlace[E any, S ~[]E](data S) iter.Seq[S] {
+	return func(yield func(S) bool) {
+		if len(data) == 0 {
+			return
 		}
-		// via https://sedgewick.io/wp-content/uploads/2022/03/2002PermGeneration.pdf
-		c := make([]int, len(data))
-		for i := range c {
+		heapPerms(data, func() bool { return yield(data) })
+	}
+}
+
+// heapPerms permutes data in place using the iterative form of Heap's
+// algorithm, calling visit after each permutation, including the first, until
+// visit returns false.
+func heapPerms[E any, S ~[]E](data S, visit func() bool) {
+	// via https://sedgewick.io/wp-content/uploads/2022/03/2002PermGeneration.pdf
+	c := make([]int, len(data))
+	if !visit() {
+		return
+	}
+	for i := 0; i < len(data); {
+		if c[i] < i {
+			if i%2 == 0 {
+				data[0], data[i] = data[i], data[0]
+			} else {
+				data[c[i]], data[i] = data[i], data[c[i]]
+			}
+			if !visit() {
+				return
+			}
+			c[i] += 1
+			i = 1
+		} else {
 			c[i] = 0
+			i += 1
 		}
-		if !yieldCopy() {
+	}
+}
+
+// PermAdjacent returns an iterator over all permutations of data, in an order
+// where each one differs from the one before by swapping a single adjacent
+// pair: the Steinhaus–Johnson–Trotter order. Along with each permutation it
+// yields i such that the elements at i and i+1 were just swapped, or -1 for the
+// first permutation, which is data as it was. This allows something computed
+// from a permutation to be updated for the next one rather than recomputed.
+//
+// Like Perm, data is permuted in place and is itself yielded every time, so it
+// must not be modified by the consumer, and must be cloned if a permutation is
+// to be retained.
+func PermAdjacent[E any, S ~[]E](data S) iter.Seq2[S, int] {
+	return func(yield func(S, int) bool) {
+		n := len(data)
+		if n == 0 {
 			return
 		}
-
-		for i := 0; i < len(data); {
-			if c[i] < i {
-				if i%2 == 0 {
-					data[0], data[i] = data[i], data[0]
-				} else {
-					data[c[i]], data[i] = data[i], data[c[i]]
-				}
-				if !yieldCopy() {
-					return
+		if !yield(data, -1) {
+			return
+		}
+		// perm is the current permutation of 0..n-1, tracking data,
+		// and dir the direction each value is moving in: -1 for left,
+		// +1 for right. Everything starts off moving left.
+		perm := make([]int, n)
+		pos := make([]int, n)
+		dir := make([]int, n)
+		for i := range perm {
+			perm[i], pos[i], dir[i] = i, i, -1
+		}
+		for {
+			// Find the largest mobile value: one that is moving
+			// towards a smaller neighbour.
+			m := -1
+			for v := n - 1; v >= 0; v-- {
+				j := pos[v] + dir[v]
+				if j >= 0 && j < n && perm[j] < v {
+					m = v
+					break
 				}
-				c[i] += 1
-				i = 1
-			} else {
-				c[i] = 0
-				i += 1
+			}
+			if m < 0 {
+				return
+			}
+			a := pos[m]
+			b := a + dir[m]
+			other := perm[b]
+			perm[a], perm[b] = other, m
+			pos[m], pos[other] = b, a
+			data[a], data[b] = data[b], data[a]
+			for v := m + 1; v < n; v++ {
+				dir[v] = -dir[v]
+			}
+			if !yield(data, min(a, b)) {
+				return
+			}
+		}
+	}
+}
+
+// PermAt returns the ith permutation of data in lexicographic order, treating
+// the elements of data as already being in ascending order. That is,
+// PermAt(data, 0) is a copy of data and PermAt(data, n!-1) is data reversed.
+// The input is not modified. It panics if i is not less than len(data)!.
+//
+// Together with PermRank this allows the space of permutations to be
+// split into ranges and processed independently.
+func PermAt[E any, S ~[]E](data S, i uint64) S {
+	if f, ok := factorial(len(data)); ok && i >= f {
+		panic(fmt.Sprintf("it.PermAt: index %d out of range for %d elements", i, len(data)))
+	}
+	// Unrank via the factorial number system: the kth digit picks which of
+	// the remaining elements goes in position k.
+	remaining := slices.Clone(data)
+	ret := make(S, 0, len(data))
+	for k := range data {
+		var digit uint64
+		if f, ok := factorial(len(data) - 1 - k); ok {
+			digit = i / f
+			i %= f
+		}
+		ret = append(ret, remaining[digit])
+		remaining = slices.Delete(remaining, int(digit), int(digit)+1)
+	}
+	return ret
+}
+
+// PermRank is the inverse of PermAt: it returns the position of perm in the
+// lexicographic ordering of the permutations of data. The elements of data
+// should be distinct, if they are not then each element of perm is matched
+// with the first unused equal element of data. It panics if perm is not a
+// permutation of data, or if its rank does not fit in a uint64 (which is only
+// possible for more than 20 elements).
+func PermRank[E comparable, S ~[]E](data, perm S) uint64 {
+	if len(data) != len(perm) {
+		panic(fmt.Sprintf("it.PermRank: perm has %d elements, data has %d", len(perm), len(data)))
+	}
+	remaining := slices.Clone(data)
+	var rank uint64
+	for k, e := range perm {
+		digit := slices.Index(remaining, e)
+		if digit == -1 {
+			panic(fmt.Sprintf("it.PermRank: %v is not a permutation of %v", perm, data))
+		}
+		f, ok := factorial(len(perm) - 1 - k)
+		if !ok && digit != 0 {
+			panic(fmt.Sprintf("it.PermRank: rank of %v overflows uint64", perm))
+		}
+		hi, lo := bits.Mul64(uint64(digit), f)
+		sum, carry := bits.Add64(rank, lo, 0)
+		if hi != 0 || carry != 0 {
+			panic(fmt.Sprintf("it.PermRank: rank of %v overflows uint64", perm))
+		}
+		rank = sum
+		remaining = slices.Delete(remaining, digit, digit+1)
+	}
+	return rank
+}
+
+// factorial returns n!, or false if it doesn't fit in a uint64.
+func factorial(n int) (uint64, bool) {
+	f := uint64(1)
+	for i := 2; i <= n; i++ {
+		if f > math.MaxUint64/uint64(i) {
+			return 0, false
+		}
+		f *= uint64(i)
+	}
+	return f, true
+}
+
+// RandomPerms returns an infinite iterator of uniformly random permutations of
+// data, drawn using r. If r is nil a private randomly seeded source is used.
+// The input is never modified, but like Perm the same slice is yielded every
+// time, so it must be cloned if it is to be retained. If data is empty then
+// nothing is yielded.
+func RandomPerms[E any, S ~[]E](data S, r *rand.Rand) iter.Seq[S] {
+	return func(yield func(S) bool) {
+		if len(data) == 0 {
+			return
+		}
+		r := randOrDefault(r)
+		ret := make(S, len(data))
+		for {
+			copy(ret, data)
+			r.Shuffle(len(ret), func(i, j int) {
+				ret[i], ret[j] = ret[j], ret[i]
+			})
+			if !yield(ret) {
+				return
 			}
 		}
 	}
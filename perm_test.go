@@ -3,8 +3,11 @@ package it
 import (
 	"fmt"
 	"iter"
+	"math"
+	"math/rand/v2"
 	"slices"
 	"sort"
+	"strconv"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -84,6 +87,98 @@ func TestPerms(t *testing.T) {
 	}
 }
 
+func TestPermInPlace(t *testing.T) {
+	for size := range 7 {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			data := make([]int, size)
+			for i := range data {
+				data[i] = i
+			}
+			var want [][]int
+			for p := range Perm(slices.Clone(data)) {
+				want = append(want, slices.Clone(p))
+			}
+			var got [][]int
+			for p := range PermInPlace(data) {
+				if size > 0 && &p[0] != &data[0] {
+					t.Fatal("didn't yield data itself")
+				}
+				got = append(got, slices.Clone(p))
+			}
+			if d := cmp.Diff(got, want); d != "" {
+				t.Fatalf("mismatch with Perm (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestPermAdjacent(t *testing.T) {
+	for size := range 8 {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			data := make([]int, size)
+			for i := range data {
+				data[i] = i
+			}
+			seen := make(map[string]bool)
+			var prev []int
+			for p, i := range PermAdjacent(data) {
+				s := fmt.Sprint(p)
+				if seen[s] {
+					t.Fatalf("permutation seen twice: %v", s)
+				}
+				seen[s] = true
+				if prev == nil {
+					if i != -1 || !slices.IsSorted(p) {
+						t.Fatalf("first yield = %v, %d, want the input and -1", p, i)
+					}
+					prev = slices.Clone(p)
+					continue
+				}
+				if i < 0 || i+1 >= size {
+					t.Fatalf("swapped index %d out of range", i)
+				}
+				// Swapping i and i+1 back gives the previous
+				// permutation.
+				undo := slices.Clone(p)
+				undo[i], undo[i+1] = undo[i+1], undo[i]
+				if !slices.Equal(undo, prev) {
+					t.Fatalf("%v doesn't follow %v by swapping %d and %d", p, prev, i, i+1)
+				}
+				copy(prev, p)
+			}
+			want, _ := factorial(size)
+			if size == 0 {
+				want = 0
+			}
+			if len(seen) != int(want) {
+				t.Fatalf("got %d permutations, want %d", len(seen), want)
+			}
+		})
+	}
+}
+
+func TestPermAdjacentExplicit(t *testing.T) {
+	type step struct {
+		Perm    string
+		Swapped int
+	}
+	var got []step
+	for p, i := range PermAdjacent([]byte("abc")) {
+		got = append(got, step{string(p), i})
+	}
+	want := []step{
+		{"abc", -1},
+		{"acb", 1},
+		{"cab", 0},
+		{"cba", 1},
+		{"bca", 0},
+		{"bac", 1},
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
 func BenchmarkPerm(b *testing.B) {
 	for size := range 10 {
 		data := make([]int, size)
@@ -96,6 +191,7 @@ func BenchmarkPerm(b *testing.B) {
 		}{
 			{name: "recursive", f: permRec[int, []int]},
 			{name: "iterative", f: permIter[int, []int]},
+			{name: "in-place", f: PermInPlace[int, []int]},
 		} {
 			b.Run(fmt.Sprintf("%d/%s", size, c.name), func(b *testing.B) {
 				for b.Loop() {
@@ -109,3 +205,103 @@ func BenchmarkPerm(b *testing.B) {
 		}
 	}
 }
+
+func TestPermAtLexicographic(t *testing.T) {
+	for size := range 7 {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			data := make([]int, size)
+			for i := range data {
+				data[i] = i
+			}
+			var want [][]int
+			for p := range Perm(slices.Clone(data)) {
+				want = append(want, slices.Clone(p))
+			}
+			if size == 0 {
+				// Perm doesn't yield anything for an empty slice,
+				// but there is exactly one empty permutation.
+				want = [][]int{{}}
+			}
+			slices.SortFunc(want, slices.Compare)
+
+			var got [][]int
+			for i := range uint64(len(want)) {
+				got = append(got, PermAt(data, i))
+			}
+			if d := cmp.Diff(got, want); d != "" {
+				t.Fatalf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestPermRankRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for _, size := range []int{1, 2, 5, 10, 20, 21, 30} {
+		data := make([]string, size)
+		for i := range data {
+			data[i] = strconv.Itoa(i)
+		}
+		limit := uint64(math.MaxUint64)
+		if f, ok := factorial(size); ok {
+			limit = f
+		}
+		for range 100 {
+			i := r.Uint64N(limit)
+			t.Run(fmt.Sprintf("%d/%d", size, i), func(t *testing.T) {
+				p := PermAt(data, i)
+				if got := PermRank(data, p); got != i {
+					t.Fatalf("PermRank(PermAt(%d)) = %d", i, got)
+				}
+			})
+		}
+	}
+}
+
+func TestPermAtOutOfRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	PermAt([]int{1, 2, 3}, 6)
+}
+
+func TestRandomPerms(t *testing.T) {
+	const (
+		size  = 4
+		draws = 24000
+	)
+	data := []int{0, 1, 2, 3}
+	r := rand.New(rand.NewPCG(1, 2))
+
+	// counts[i][j] is the number of times j was at position i.
+	var counts [size][size]int
+	for p := range Take(RandomPerms(data, r), draws) {
+		sorted := slices.Sorted(slices.Values(p))
+		if d := cmp.Diff(sorted, data); d != "" {
+			t.Fatalf("not a permutation: %v", p)
+		}
+		for i, j := range p {
+			counts[i][j]++
+		}
+	}
+	if d := cmp.Diff(data, []int{0, 1, 2, 3}); d != "" {
+		t.Fatalf("input modified (-got, +want):\n%v", d)
+	}
+	// Each element should be at each position about 1/size of the time.
+	want := draws / size
+	for i := range counts {
+		for j, c := range counts[i] {
+			if c < want*9/10 || c > want*11/10 {
+				t.Errorf("%d at position %d %d times, want about %d", j, i, c, want)
+			}
+		}
+	}
+}
+
+func TestRandomPermsEmpty(t *testing.T) {
+	if got := slices.Collect(Take(RandomPerms([]int{}, nil), 10)); got != nil {
+		t.Fatalf("expected nothing, got %v", got)
+	}
+}
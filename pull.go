@@ -0,0 +1,233 @@
+package it
+
+import (
+	"iter"
+	"runtime"
+)
+
+// Peekable wraps an iterator to allow looking at the next value without
+// consuming it. It is built on iter.Pull, so Stop must be called once it is no
+// longer needed, unless it has been exhausted. A Peekable is not safe for
+// concurrent use.
+type Peekable[A any] struct {
+	next   func() (A, bool)
+	stop   func()
+	peeked bool
+	head   A
+	headOK bool
+}
+
+// NewPeekable returns a Peekable over the values of it.
+func NewPeekable[A any](it iter.Seq[A]) *Peekable[A] {
+	next, stop := iter.Pull(it)
+	return &Peekable[A]{next: next, stop: stop}
+}
+
+// Peek returns the next value without consuming it, so that the following
+// call to Peek or Next returns the same value. The boolean is false if there
+// are no more values.
+func (p *Peekable[A]) Peek() (A, bool) {
+	if !p.peeked {
+		p.head, p.headOK = p.next()
+		p.peeked = true
+	}
+	return p.head, p.headOK
+}
+
+// Next consumes and returns the next value. The boolean is false if there are
+// no more values.
+func (p *Peekable[A]) Next() (A, bool) {
+	if !p.peeked {
+		return p.next()
+	}
+	a, ok := p.head, p.headOK
+	var zero A
+	p.head, p.headOK, p.peeked = zero, false, false
+	return a, ok
+}
+
+// Seq returns an iterator over the remaining values, starting with the peeked
+// value if there is one. Values yielded by it are consumed from p, but if the
+// consumer stops early the rest are still available, so Stop must still be
+// called.
+func (p *Peekable[A]) Seq() iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for {
+			a, ok := p.Next()
+			if !ok || !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// Stop releases the underlying iterator. After Stop, there are no more values.
+func (p *Peekable[A]) Stop() {
+	p.stop()
+	var zero A
+	p.head, p.headOK, p.peeked = zero, false, true
+}
+
+// Pushback wraps an iterator to allow values to be pushed back to be read
+// again, which is useful for tokenizers and parsers. Pushed back values are
+// returned before anything further from the underlying iterator, most recently
+// pushed first. It is built on iter.Pull, so Stop must be called once it is no
+// longer needed, unless the underlying iterator has been exhausted. A Pushback
+// is not safe for concurrent use.
+type Pushback[A any] struct {
+	next  func() (A, bool)
+	stop  func()
+	stack []A
+}
+
+// NewPushback returns a Pushback over the values of it.
+func NewPushback[A any](it iter.Seq[A]) *Pushback[A] {
+	next, stop := iter.Pull(it)
+	return &Pushback[A]{next: next, stop: stop}
+}
+
+// Next returns the most recently pushed back value if there is one, otherwise
+// the next value from the underlying iterator. The boolean is false if there
+// are no more values.
+func (p *Pushback[A]) Next() (A, bool) {
+	if n := len(p.stack); n > 0 {
+		a := p.stack[n-1]
+		var zero A
+		p.stack[n-1] = zero
+		p.stack = p.stack[:n-1]
+		return a, true
+	}
+	return p.next()
+}
+
+// Unread pushes a back, so that it is returned by the next call to Next. Any
+// number of values can be pushed back, it doesn't have to be a value that was
+// previously returned by Next, and it works even once the underlying iterator
+// is exhausted or stopped.
+func (p *Pushback[A]) Unread(a A) {
+	p.stack = append(p.stack, a)
+}
+
+// Seq returns an iterator over the remaining values, including any that have
+// been pushed back. Values yielded by it are consumed from p, but if the
+// consumer stops early the rest are still available, so Stop must still be
+// called.
+func (p *Pushback[A]) Seq() iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for {
+			a, ok := p.Next()
+			if !ok || !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// Stop releases the underlying iterator, after which Next only returns values
+// that have been pushed back. It is safe to call Stop more than once.
+func (p *Pushback[A]) Stop() {
+	p.stop()
+}
+
+// HeadTail splits the first value off it, returning it along with an iterator
+// over the rest of the values. The boolean is false if it is empty, in which
+// case the tail is empty too. The tail is single use: ranging over it a second
+// time yields nothing.
+//
+// it is left part way through until the tail is ranged over, so callers must
+// always range over the tail, breaking out straight away if they don't want the
+// rest. A tail that is dropped without being ranged over is only stopped by a
+// cleanup once it has been garbage collected, which may be much later or, if
+// the program exits first, never.
+func HeadTail[A any](it iter.Seq[A]) (head A, tail iter.Seq[A], ok bool) {
+	head, p, ok := pullHead(it)
+	return head, p.seq, ok
+}
+
+// PeekEmpty reports whether it is empty, and returns an iterator that yields
+// all of its values, including the one that had to be pulled to find out. Like
+// the tail from HeadTail, the returned iterator is single use, and callers must
+// always range over it rather than relying on it being stopped when garbage
+// collected.
+func PeekEmpty[A any](it iter.Seq[A]) (bool, iter.Seq[A]) {
+	head, p, ok := pullHead(it)
+	if ok {
+		p.head, p.hasHead = head, true
+	}
+	return !ok, p.seq
+}
+
+// pullHead pulls the first value from it and returns it along with the rest.
+// If it isn't empty, it stays paused part way through until the rest is ranged
+// over. The cleanup that stops it if the rest is dropped is a last resort for
+// callers that get this wrong, not something to rely on.
+func pullHead[A any](it iter.Seq[A]) (A, *pulled[A], bool) {
+	next, stop := iter.Pull(it)
+	head, ok := next()
+	if !ok {
+		stop()
+		return head, &pulled[A]{used: true}, false
+	}
+	p := &pulled[A]{next: next, stop: stop}
+	runtime.AddCleanup(p, func(stop func()) { stop() }, stop)
+	return head, p, true
+}
+
+// pulled is a single use iterator backed by iter.Pull, optionally with a value
+// to yield before the rest.
+type pulled[A any] struct {
+	next    func() (A, bool)
+	stop    func()
+	used    bool
+	head    A
+	hasHead bool
+}
+
+func (p *pulled[A]) seq(yield func(A) bool) {
+	if p.used {
+		return
+	}
+	p.used = true
+	defer p.stop()
+	if p.hasHead && !yield(p.head) {
+		return
+	}
+	for {
+		a, ok := p.next()
+		if !ok || !yield(a) {
+			return
+		}
+	}
+}
+
+// NextN calls next, as returned by iter.Pull, up to n times and returns the
+// values it produced. The result has fewer than n values only if next ran out.
+// It panics if n is negative.
+func NextN[A any](next func() (A, bool), n int) []A {
+	checkCount("it.NextN", n)
+	var values []A
+	for range n {
+		a, ok := next()
+		if !ok {
+			break
+		}
+		values = append(values, a)
+	}
+	return values
+}
+
+// PullSeq turns a pull function, as returned by iter.Pull, back into an
+// iterator, so that it can be used with the rest of this package. The result
+// shares its position with next: ranging over it consumes values from next,
+// and ranging over it again continues from wherever next has got to. It does
+// not call the corresponding stop function.
+func PullSeq[A any](next func() (A, bool)) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for {
+			a, ok := next()
+			if !ok || !yield(a) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package it
+
+import (
+	"flag"
+	"iter"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// countingSeq returns an iterator over values that counts how many have been
+// pulled, and records whether it has returned.
+func countingSeq[A any](values ...A) (seq iter.Seq[A], pulled *int, returned *bool) {
+	pulled, returned = new(int), new(bool)
+	return func(yield func(A) bool) {
+		defer func() { *returned = true }()
+		for _, v := range values {
+			*pulled++
+			if !yield(v) {
+				return
+			}
+		}
+	}, pulled, returned
+}
+
+func TestPeekable(t *testing.T) {
+	src, pulled, returned := countingSeq(1, 2, 3)
+	p := NewPeekable(src)
+	defer p.Stop()
+
+	for range 3 {
+		if v, ok := p.Peek(); v != 1 || !ok {
+			t.Fatalf("Peek() = %d, %v, want 1, true", v, ok)
+		}
+	}
+	if *pulled != 1 {
+		t.Fatalf("pulled %d values after peeking, want 1", *pulled)
+	}
+	if v, ok := p.Next(); v != 1 || !ok {
+		t.Fatalf("Next() = %d, %v, want 1, true", v, ok)
+	}
+	if v, ok := p.Next(); v != 2 || !ok {
+		t.Fatalf("Next() = %d, %v, want 2, true", v, ok)
+	}
+	if v, ok := p.Peek(); v != 3 || !ok {
+		t.Fatalf("Peek() = %d, %v, want 3, true", v, ok)
+	}
+	if d := cmp.Diff(slices.Collect(p.Seq()), []int{3}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if v, ok := p.Peek(); ok {
+		t.Fatalf("Peek() = %d, %v after end", v, ok)
+	}
+	if v, ok := p.Next(); ok {
+		t.Fatalf("Next() = %d, %v after end", v, ok)
+	}
+	if !*returned {
+		t.Fatal("source not finished")
+	}
+}
+
+func TestPeekableSchema(t *testing.T) {
+	// Look at the first record, then process all of them.
+	p := NewPeekable(slices.Values([]string{"header", "a", "b"}))
+	defer p.Stop()
+	first, _ := p.Peek()
+	if first != "header" {
+		t.Fatalf("Peek() = %q", first)
+	}
+	if d := cmp.Diff(slices.Collect(p.Seq()), []string{"header", "a", "b"}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestPeekableStop(t *testing.T) {
+	src, _, returned := countingSeq(1, 2, 3)
+	p := NewPeekable(src)
+	p.Peek()
+	// Breaking out of Seq leaves the rest available.
+	for range p.Seq() {
+		break
+	}
+	if v, ok := p.Next(); v != 2 || !ok {
+		t.Fatalf("Next() = %d, %v, want 2, true", v, ok)
+	}
+	p.Stop()
+	if !*returned {
+		t.Fatal("source not stopped")
+	}
+	if v, ok := p.Peek(); ok {
+		t.Fatalf("Peek() = %d, %v after Stop", v, ok)
+	}
+	if v, ok := p.Next(); ok {
+		t.Fatalf("Next() = %d, %v after Stop", v, ok)
+	}
+}
+
+func TestPushback(t *testing.T) {
+	src, pulled, returned := countingSeq(1, 2, 3, 4)
+	p := NewPushback(src)
+	defer p.Stop()
+
+	next := func(want int) {
+		t.Helper()
+		if v, ok := p.Next(); v != want || !ok {
+			t.Fatalf("Next() = %d, %v, want %d, true", v, ok, want)
+		}
+	}
+	next(1)
+	next(2)
+	p.Unread(2)
+	p.Unread(20)
+	// Last in, first out, then back to the source.
+	next(20)
+	next(2)
+	next(3)
+	if *pulled != 3 {
+		t.Fatalf("pulled %d values, want 3", *pulled)
+	}
+
+	// Interleaved with Seq.
+	p.Unread(30)
+	for v := range p.Seq() {
+		if v != 30 {
+			t.Fatalf("Seq yielded %d first, want 30", v)
+		}
+		break
+	}
+	p.Unread(31)
+	if d := cmp.Diff(slices.Collect(p.Seq()), []int{31, 4}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if !*returned {
+		t.Fatal("source not finished")
+	}
+
+	// Unread after exhaustion.
+	if v, ok := p.Next(); ok {
+		t.Fatalf("Next() = %d, %v after end", v, ok)
+	}
+	p.Unread(5)
+	p.Unread(6)
+	if d := cmp.Diff(slices.Collect(p.Seq()), []int{6, 5}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if v, ok := p.Next(); ok {
+		t.Fatalf("Next() = %d, %v after end", v, ok)
+	}
+}
+
+func TestPushbackStop(t *testing.T) {
+	src, pulled, returned := countingSeq(1, 2, 3)
+	p := NewPushback(src)
+	p.Next()
+	p.Unread(10)
+	p.Stop()
+	if !*returned {
+		t.Fatal("source not stopped")
+	}
+	// Pushed back values survive Stop, the source doesn't.
+	if d := cmp.Diff(slices.Collect(p.Seq()), []int{10}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if *pulled != 1 {
+		t.Fatalf("pulled %d values, want 1", *pulled)
+	}
+	p.Stop()
+}
+
+func TestHeadTail(t *testing.T) {
+	src, pulled, returned := countingSeq(1, 2, 3, 4)
+	head, tail, ok := HeadTail(src)
+	if head != 1 || !ok {
+		t.Fatalf("HeadTail() = %d, _, %v, want 1, _, true", head, ok)
+	}
+	if *pulled != 1 {
+		t.Fatalf("pulled %d values, want 1", *pulled)
+	}
+	if d := cmp.Diff(slices.Collect(tail), []int{2, 3, 4}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if !*returned {
+		t.Fatal("source not finished")
+	}
+	// Second time round there's nothing.
+	if got := slices.Collect(tail); got != nil {
+		t.Fatalf("expected nothing, got %v", got)
+	}
+}
+
+func TestHeadTailEmpty(t *testing.T) {
+	src, _, returned := countingSeq[int]()
+	head, tail, ok := HeadTail(src)
+	if head != 0 || ok {
+		t.Fatalf("HeadTail() = %d, _, %v, want 0, _, false", head, ok)
+	}
+	if !*returned {
+		t.Fatal("source not finished")
+	}
+	if got := slices.Collect(tail); got != nil {
+		t.Fatalf("expected nothing, got %v", got)
+	}
+}
+
+func TestHeadTailEarlyBreak(t *testing.T) {
+	src, pulled, returned := countingSeq(1, 2, 3, 4)
+	_, tail, _ := HeadTail(src)
+	for range tail {
+		break
+	}
+	if !*returned {
+		t.Fatal("source not stopped")
+	}
+	if *pulled != 2 {
+		t.Fatalf("pulled %d values, want 2", *pulled)
+	}
+}
+
+var gcTests = flag.Bool("gctests", false, "run tests that depend on when the garbage collector runs")
+
+func TestHeadTailNeverRanged(t *testing.T) {
+	if !*gcTests {
+		t.Skip("depends on garbage collection, run with -gctests")
+	}
+	// The cleanup runs in another goroutine.
+	var returned atomic.Bool
+	src := func(yield func(int) bool) {
+		defer returned.Store(true)
+		for i := 0; yield(i); i++ {
+		}
+	}
+	func() {
+		_, tail, _ := HeadTail(src)
+		_ = tail
+	}()
+	// The source is stopped by a cleanup once the tail is unreachable,
+	// which may take a few goes.
+	for range 100 {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+		if returned.Load() {
+			return
+		}
+	}
+	t.Fatal("source never stopped")
+}
+
+func TestPeekEmpty(t *testing.T) {
+	for _, in := range [][]int{nil, {1}, {1, 2, 3}} {
+		src, pulled, returned := countingSeq(in...)
+		empty, seq := PeekEmpty(src)
+		if want := len(in) == 0; empty != want {
+			t.Errorf("PeekEmpty(%v) = %v, want %v", in, empty, want)
+		}
+		if wantPulled := min(len(in), 1); *pulled != wantPulled {
+			t.Errorf("pulled %d values from %v, want %d", *pulled, in, wantPulled)
+		}
+		if d := cmp.Diff(slices.Collect(seq), in); d != "" {
+			t.Errorf("mismatch (-got, +want):\n%v", d)
+		}
+		if *pulled != len(in) || !*returned {
+			t.Errorf("pulled %d values from %v, returned: %v", *pulled, in, *returned)
+		}
+		if got := slices.Collect(seq); got != nil {
+			t.Errorf("second time round got %v, want nothing", got)
+		}
+	}
+}
+
+func TestPeekEmptyEarlyBreak(t *testing.T) {
+	src, pulled, returned := countingSeq(1, 2, 3)
+	_, seq := PeekEmpty(src)
+	for range seq {
+		break
+	}
+	if *pulled != 1 || !*returned {
+		t.Errorf("pulled %d values, returned: %v, want 1, true", *pulled, *returned)
+	}
+}
+
+func TestNextNAndPullSeq(t *testing.T) {
+	next, stop := iter.Pull(slices.Values([]int{1, 2, 3, 4, 5, 6, 7}))
+	defer stop()
+
+	if d := cmp.Diff(NextN(next, 2), []int{1, 2}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if got := NextN(next, 0); got != nil {
+		t.Fatalf("NextN(0) = %v", got)
+	}
+	// Bulk processing of part of the rest.
+	if d := cmp.Diff(slices.Collect(Limit(PullSeq(next), 2)), []int{3, 4}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(NextN(next, 10), []int{5, 6, 7}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if got := slices.Collect(PullSeq(next)); got != nil {
+		t.Fatalf("expected nothing, got %v", got)
+	}
+}
@@ -0,0 +1,239 @@
+package it
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// randOrDefault returns r, or if r is nil a new randomly seeded source that
+// isn't shared with anything else.
+func randOrDefault(r *rand.Rand) *rand.Rand {
+	if r != nil {
+		return r
+	}
+	return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+}
+
+// Shuffled returns an iterator that yields the elements of it in a uniformly
+// random order, drawn using r. If r is nil a private randomly seeded source is
+// used. The whole input must be collected before the first element can be
+// yielded, so this takes O(n) memory.
+//
+// Every time the returned iterator is ranged over it collects the input again
+// and draws a new shuffle, so two ranges give two independent orderings. With
+// a seeded r the sequence of orderings is deterministic.
+func Shuffled[A any](it iter.Seq[A], r *rand.Rand) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		r := randOrDefault(r)
+		as := slices.Collect(it)
+		r.Shuffle(len(as), func(i, j int) {
+			as[i], as[j] = as[j], as[i]
+		})
+		for _, a := range as {
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// Sample returns k elements chosen uniformly at random from it, using r. If r
+// is nil a private randomly seeded source is used. It makes a single pass over
+// the input and only ever holds k elements in memory, so it is suitable for
+// sequences far too large to collect. Fewer than k elements are returned only
+// if the sequence has fewer than k elements. The order of the returned
+// elements is not meaningful. It panics if k is negative.
+func Sample[A any](it iter.Seq[A], k int, r *rand.Rand) []A {
+	res := NewReservoir[A](k, r)
+	for a := range it {
+		res.Add(a)
+	}
+	return res.Sample()
+}
+
+// Reservoir maintains a uniform random sample of fixed size from a stream of
+// values, for when the sample needs to be inspected periodically while the
+// stream is still going. See Sample for the simple case. A Reservoir is not
+// safe for concurrent use.
+type Reservoir[A any] struct {
+	r      *rand.Rand
+	sample []A
+	seen   int
+}
+
+// NewReservoir returns a new Reservoir that holds a sample of up to k values,
+// chosen using r. If r is nil a private randomly seeded source is used. It
+// panics if k is negative.
+func NewReservoir[A any](k int, r *rand.Rand) *Reservoir[A] {
+	if k < 0 {
+		panic(fmt.Sprintf("it.NewReservoir: negative sample size %d", k))
+	}
+	return &Reservoir[A]{
+		r:      randOrDefault(r),
+		sample: make([]A, 0, k),
+	}
+}
+
+// Add offers a to the reservoir.
+func (res *Reservoir[A]) Add(a A) {
+	// Algorithm R.
+	res.seen++
+	if len(res.sample) < cap(res.sample) {
+		res.sample = append(res.sample, a)
+		return
+	}
+	if j := res.r.IntN(res.seen); j < len(res.sample) {
+		res.sample[j] = a
+	}
+}
+
+// Seen returns the number of values that have been added.
+func (res *Reservoir[A]) Seen() int { return res.seen }
+
+// Sample returns a copy of the current sample.
+func (res *Reservoir[A]) Sample() []A { return slices.Clone(res.sample) }
+
+// WeightedSample returns an infinite iterator of values drawn at random from
+// items, with probability proportional to their weights, using r. If r is nil
+// a private randomly seeded source is used.
+//
+// Unlike most of this package, items is consumed immediately, when
+// WeightedSample is called, to build an alias table that makes each draw
+// constant time. It panics if any weight is not a positive, finite number, or
+// if the total of the weights is too large to represent as a float64. If
+// items is empty the returned iterator yields nothing.
+func WeightedSample[A any](items iter.Seq2[A, float64], r *rand.Rand) iter.Seq[A] {
+	var (
+		values  []A
+		weights []float64
+		total   float64
+	)
+	for a, w := range items {
+		if !(w > 0) || math.IsInf(w, 1) {
+			panic(fmt.Sprintf("it.WeightedSample: invalid weight %v for %v", w, a))
+		}
+		values = append(values, a)
+		weights = append(weights, w)
+		total += w
+	}
+	if math.IsInf(total, 1) {
+		panic("it.WeightedSample: total weight overflows float64")
+	}
+	prob, alias := aliasTable(weights, total)
+	return func(yield func(A) bool) {
+		if len(values) == 0 {
+			return
+		}
+		r := randOrDefault(r)
+		for {
+			i := r.IntN(len(values))
+			if r.Float64() >= prob[i] {
+				i = alias[i]
+			}
+			if !yield(values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// aliasTable builds the tables for Vose's alias method. To draw a sample, pick
+// a column i uniformly, then choose i with probability prob[i] and alias[i]
+// otherwise.
+func aliasTable(weights []float64, total float64) (prob []float64, alias []int) {
+	n := len(weights)
+	prob = make([]float64, n)
+	alias = make([]int, n)
+	scaled := make([]float64, n)
+	var small, large []int
+	for i, w := range weights {
+		scaled[i] = w * float64(n) / total
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	for len(small) > 0 && len(large) > 0 {
+		s, l := small[len(small)-1], large[len(large)-1]
+		small = small[:len(small)-1]
+		prob[s] = scaled[s]
+		alias[s] = l
+		// The large column donates enough to fill up the small one.
+		scaled[l] -= 1 - scaled[s]
+		if scaled[l] < 1 {
+			large = large[:len(large)-1]
+			small = append(small, l)
+		}
+	}
+	// Anything left over is full, up to floating point error.
+	for _, i := range slices.Concat(small, large) {
+		prob[i] = 1
+	}
+	return prob, alias
+}
+
+// SampleP returns an iterator that yields each element of it independently
+// with probability p, preserving their order, using r. If r is nil a private
+// randomly seeded source is used. It panics if p is not in [0, 1].
+func SampleP[A any](it iter.Seq[A], p float64, r *rand.Rand) iter.Seq[A] {
+	if !(p >= 0 && p <= 1) {
+		panic(fmt.Sprintf("it.SampleP: probability %v not in [0, 1]", p))
+	}
+	return func(yield func(A) bool) {
+		r := randOrDefault(r)
+		for a := range it {
+			if r.Float64() >= p {
+				continue
+			}
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// RandomInts returns an infinite iterator of integers drawn uniformly from
+// [lo, hi) using r. If r is nil a private randomly seeded source is used. It
+// panics if lo is not less than hi.
+func RandomInts(lo, hi int, r *rand.Rand) iter.Seq[int] {
+	if lo >= hi {
+		panic(fmt.Sprintf("it.RandomInts: empty range [%d, %d)", lo, hi))
+	}
+	// Unsigned, so that ranges wider than the largest int still work.
+	span := uint64(hi) - uint64(lo)
+	return func(yield func(int) bool) {
+		r := randOrDefault(r)
+		for yield(lo + int(r.Uint64N(span))) {
+		}
+	}
+}
+
+// RandomFloats returns an infinite iterator of floats drawn uniformly from
+// [0, 1) using r. If r is nil a private randomly seeded source is used.
+func RandomFloats(r *rand.Rand) iter.Seq[float64] {
+	return func(yield func(float64) bool) {
+		r := randOrDefault(r)
+		for yield(r.Float64()) {
+		}
+	}
+}
+
+// RandomChoice returns an infinite iterator of elements chosen uniformly from
+// opts using r. If r is nil a private randomly seeded source is used. opts is
+// copied, so changing it afterwards doesn't affect the choices. It panics if
+// opts is empty.
+func RandomChoice[A any](opts []A, r *rand.Rand) iter.Seq[A] {
+	if len(opts) == 0 {
+		panic("it.RandomChoice: no options")
+	}
+	opts = slices.Clone(opts)
+	return func(yield func(A) bool) {
+		r := randOrDefault(r)
+		for yield(opts[r.IntN(len(opts))]) {
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package it
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestShuffled(t *testing.T) {
+	values := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	shuffled := Shuffled(slices.Values(values), rand.New(rand.NewPCG(1, 2)))
+	first := slices.Collect(shuffled)
+	second := slices.Collect(shuffled)
+
+	for _, got := range [][]int{first, second} {
+		if d := cmp.Diff(slices.Sorted(slices.Values(got)), values); d != "" {
+			t.Fatalf("not a shuffle of the input (-got, +want):\n%v", d)
+		}
+	}
+	if slices.Equal(first, second) {
+		t.Fatalf("ranging twice gave the same order: %v", first)
+	}
+
+	// Same seed, same results.
+	again := Shuffled(slices.Values(values), rand.New(rand.NewPCG(1, 2)))
+	if d := cmp.Diff(slices.Collect(again), first); d != "" {
+		t.Fatalf("not deterministic (-got, +want):\n%v", d)
+	}
+}
+
+func TestShuffledEmpty(t *testing.T) {
+	if got := slices.Collect(Shuffled(slices.Values([]int{}), nil)); got != nil {
+		t.Fatalf("expected nothing, got %v", got)
+	}
+}
+
+func TestSample(t *testing.T) {
+	const (
+		n      = 10
+		k      = 3
+		trials = 10000
+	)
+	r := rand.New(rand.NewPCG(1, 2))
+	values := make([]int, n)
+	for i := range values {
+		values[i] = i
+	}
+
+	counts := make([]int, n)
+	for range trials {
+		s := Sample(slices.Values(values), k, r)
+		if len(s) != k {
+			t.Fatalf("got %d samples, want %d", len(s), k)
+		}
+		for _, v := range s {
+			counts[v]++
+		}
+	}
+	// Every element should be picked in about k/n of the trials.
+	want := trials * k / n
+	for v, c := range counts {
+		if c < want*9/10 || c > want*11/10 {
+			t.Errorf("%d picked %d times, want about %d", v, c, want)
+		}
+	}
+}
+
+func TestSampleShort(t *testing.T) {
+	got := Sample(slices.Values([]int{1, 2, 3}), 5, nil)
+	if d := cmp.Diff(slices.Sorted(slices.Values(got)), []int{1, 2, 3}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestSampleDeterministic(t *testing.T) {
+	values := make([]int, 1000)
+	for i := range values {
+		values[i] = i
+	}
+	a := Sample(slices.Values(values), 10, rand.New(rand.NewPCG(3, 4)))
+	b := Sample(slices.Values(values), 10, rand.New(rand.NewPCG(3, 4)))
+	if d := cmp.Diff(a, b); d != "" {
+		t.Fatalf("not deterministic (-got, +want):\n%v", d)
+	}
+}
+
+func TestReservoir(t *testing.T) {
+	res := NewReservoir[int](2, rand.New(rand.NewPCG(1, 2)))
+	if got := res.Sample(); len(got) != 0 {
+		t.Fatalf("expected empty sample, got %v", got)
+	}
+	res.Add(1)
+	snapshot := res.Sample()
+	if d := cmp.Diff(snapshot, []int{1}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	for i := range 100 {
+		res.Add(i + 2)
+	}
+	if got := res.Seen(); got != 101 {
+		t.Fatalf("Seen() = %d, want 101", got)
+	}
+	if got := len(res.Sample()); got != 2 {
+		t.Fatalf("got %d samples, want 2", got)
+	}
+	// Earlier snapshots are unaffected by later additions.
+	if d := cmp.Diff(snapshot, []int{1}); d != "" {
+		t.Fatalf("snapshot changed (-got, +want):\n%v", d)
+	}
+}
+
+func TestWeightedSample(t *testing.T) {
+	const draws = 100000
+	// A slice rather than a map, so that the input order and so the draws
+	// for the fixed seed are deterministic.
+	weights := []Pair[string, float64]{
+		{"a", 1},
+		{"b", 2},
+		{"c", 3},
+		{"d", 0.5},
+		{"e", 3.5},
+	}
+	var total float64
+	for _, p := range weights {
+		total += p.B
+	}
+
+	counts := make(map[string]int)
+	r := rand.New(rand.NewPCG(1, 2))
+	for v := range Take(WeightedSample(Unpair(slices.Values(weights)), r), draws) {
+		counts[v]++
+	}
+	for _, p := range weights {
+		want := p.B / total
+		got := float64(counts[p.A]) / draws
+		if math.Abs(got-want) > 0.01 {
+			t.Errorf("%q drawn with frequency %.3f, want %.3f", p.A, got, want)
+		}
+	}
+}
+
+func TestWeightedSampleSingle(t *testing.T) {
+	got := slices.Collect(Take(WeightedSample(maps.All(map[int]float64{7: 0.1}), nil), 5))
+	if d := cmp.Diff(got, []int{7, 7, 7, 7, 7}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestWeightedSampleInvalid(t *testing.T) {
+	for _, w := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		t.Run(fmt.Sprint(w), func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			WeightedSample(maps.All(map[string]float64{"a": 1, "b": w}), nil)
+		})
+	}
+}
+
+func TestWeightedSampleOverflow(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	// Each weight is fine, but the total isn't.
+	WeightedSample(Unpair(slices.Values([]Pair[string, float64]{{"a", math.MaxFloat64}, {"b", math.MaxFloat64}})), nil)
+}
+
+func TestSampleP(t *testing.T) {
+	const n = 100000
+	values := make([]int, n)
+	for i := range values {
+		values[i] = i
+	}
+	for _, p := range []float64{0, 0.01, 0.25, 0.5, 0.9, 1} {
+		t.Run(fmt.Sprint(p), func(t *testing.T) {
+			r := rand.New(rand.NewPCG(1, 2))
+			got := slices.Collect(SampleP(slices.Values(values), p, r))
+			if !slices.IsSorted(got) {
+				t.Fatal("order not preserved")
+			}
+			switch p {
+			case 0:
+				if len(got) != 0 {
+					t.Fatalf("p=0 kept %d elements", len(got))
+				}
+			case 1:
+				if d := cmp.Diff(got, values); d != "" {
+					t.Fatalf("p=1 mismatch (-got, +want):\n%v", d)
+				}
+			default:
+				rate := float64(len(got)) / n
+				if math.Abs(rate-p) > 0.01 {
+					t.Fatalf("kept %.4f of elements, want about %v", rate, p)
+				}
+			}
+		})
+	}
+}
+
+func TestSamplePInvalid(t *testing.T) {
+	for _, p := range []float64{-0.1, 1.1, math.NaN()} {
+		t.Run(fmt.Sprint(p), func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			SampleP(slices.Values([]int{1}), p, nil)
+		})
+	}
+}
+
+func TestRandomInts(t *testing.T) {
+	for _, c := range []struct{ lo, hi int }{
+		{0, 1},
+		{0, 10},
+		{-5, 5},
+		{math.MinInt, math.MaxInt},
+	} {
+		t.Run(fmt.Sprintf("[%d,%d)", c.lo, c.hi), func(t *testing.T) {
+			seen := make(map[int]bool)
+			for v := range Take(RandomInts(c.lo, c.hi, rand.New(rand.NewPCG(1, 2))), 1000) {
+				if v < c.lo || v >= c.hi {
+					t.Fatalf("got %d, outside [%d, %d)", v, c.lo, c.hi)
+				}
+				seen[v] = true
+			}
+			if span := c.hi - c.lo; span > 0 && span <= 10 && len(seen) != span {
+				t.Errorf("only saw %v", slices.Sorted(maps.Keys(seen)))
+			}
+		})
+	}
+}
+
+func TestRandomFloats(t *testing.T) {
+	var sum float64
+	for v := range Take(RandomFloats(rand.New(rand.NewPCG(1, 2))), 10000) {
+		if v < 0 || v >= 1 {
+			t.Fatalf("got %v, outside [0, 1)", v)
+		}
+		sum += v
+	}
+	if mean := sum / 10000; math.Abs(mean-0.5) > 0.01 {
+		t.Errorf("mean %v, want about 0.5", mean)
+	}
+}
+
+func TestRandomChoice(t *testing.T) {
+	opts := []string{"a", "b", "c"}
+	counts := make(map[string]int)
+	for v := range Take(RandomChoice(opts, rand.New(rand.NewPCG(1, 2))), 30000) {
+		counts[v]++
+	}
+	for _, o := range opts {
+		if math.Abs(float64(counts[o])/30000-1.0/3) > 0.01 {
+			t.Errorf("%q chosen %d times out of 30000", o, counts[o])
+		}
+	}
+	if len(counts) != len(opts) {
+		t.Errorf("chose %v, want only %v", counts, opts)
+	}
+}
+
+func TestRandomChoiceCopiesOpts(t *testing.T) {
+	opts := []int{1, 1, 1}
+	seq := RandomChoice(opts, nil)
+	opts[0], opts[1], opts[2] = 2, 2, 2
+	for v := range Take(seq, 100) {
+		if v != 1 {
+			t.Fatalf("got %d after changing opts", v)
+		}
+	}
+}
+
+func TestRandomDeterministic(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		seq  func(*rand.Rand) iter.Seq[string]
+	}{{
+		name: "RandomInts",
+		seq: func(r *rand.Rand) iter.Seq[string] {
+			return Map(RandomInts(-100, 100, r), func(v int) string { return fmt.Sprint(v) })
+		},
+	}, {
+		name: "RandomFloats",
+		seq: func(r *rand.Rand) iter.Seq[string] {
+			return Map(RandomFloats(r), func(v float64) string { return fmt.Sprint(v) })
+		},
+	}, {
+		name: "RandomChoice",
+		seq: func(r *rand.Rand) iter.Seq[string] {
+			return RandomChoice([]string{"a", "b", "c", "d"}, r)
+		},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			a := slices.Collect(Take(c.seq(rand.New(rand.NewPCG(3, 4))), 20))
+			b := slices.Collect(Take(c.seq(rand.New(rand.NewPCG(3, 4))), 20))
+			if d := cmp.Diff(a, b); d != "" {
+				t.Errorf("same seed gave different values (-first, +second):\n%v", d)
+			}
+			other := slices.Collect(Take(c.seq(rand.New(rand.NewPCG(5, 6))), 20))
+			if slices.Equal(a, other) {
+				t.Errorf("different seeds gave the same values: %v", a)
+			}
+		})
+	}
+}
+
+func TestRandomInvalid(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		f    func()
+	}{
+		{name: "RandomInts-equal", f: func() { RandomInts(1, 1, nil) }},
+		{name: "RandomInts-reversed", f: func() { RandomInts(2, 1, nil) }},
+		{name: "RandomChoice-empty", f: func() { RandomChoice([]int{}, nil) }},
+		{name: "RandomChoice-nil", f: func() { RandomChoice[int](nil, nil) }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			c.f()
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package it
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+)
+
+// Record returns an iterator that yields the same values as it, appending each
+// one to *sink as it goes, so that what flowed through a pipeline can be
+// inspected or replayed later with Replay. Values are recorded just before
+// they are yielded, so a value the consumer stopped on is included. See
+// Recorder to keep only the most recent values.
+func Record[A any](it iter.Seq[A], sink *[]A) iter.Seq[A] {
+	return Inspect(it, func(a A) { *sink = append(*sink, a) })
+}
+
+// Replay returns an iterator over recorded values, as from Record or
+// Recorder.Values.
+func Replay[A any](recorded []A) iter.Seq[A] {
+	return slices.Values(recorded)
+}
+
+// Recorder keeps the most recent values that flowed through an iterator, up to
+// a fixed capacity, dropping the oldest once it is full. A Recorder is not safe
+// for concurrent use.
+type Recorder[A any] struct {
+	// buf is a ring buffer of the recorded values, the oldest at next once
+	// it has filled up.
+	buf  []A
+	next int
+	seen int
+}
+
+// NewRecorder returns a Recorder that keeps up to n values. It panics if n is
+// not positive.
+func NewRecorder[A any](n int) *Recorder[A] {
+	if n <= 0 {
+		panic(fmt.Sprintf("it.NewRecorder: invalid capacity %d", n))
+	}
+	return &Recorder[A]{buf: make([]A, 0, n)}
+}
+
+// Add records a, dropping the oldest value if the recorder is full.
+func (r *Recorder[A]) Add(a A) {
+	r.seen++
+	if len(r.buf) < cap(r.buf) {
+		r.buf = append(r.buf, a)
+		return
+	}
+	r.buf[r.next] = a
+	r.next = (r.next + 1) % len(r.buf)
+}
+
+// Record returns an iterator that yields the same values as it, adding each
+// one to r just before it is yielded.
+func (r *Recorder[A]) Record(it iter.Seq[A]) iter.Seq[A] {
+	return Inspect(it, r.Add)
+}
+
+// Seen returns the number of values that have been recorded, including any
+// that have since been dropped.
+func (r *Recorder[A]) Seen() int { return r.seen }
+
+// Truncated reports whether any values have been dropped to make room for
+// newer ones.
+func (r *Recorder[A]) Truncated() bool { return r.seen > len(r.buf) }
+
+// Values returns a copy of the values currently held, oldest first.
+func (r *Recorder[A]) Values() []A {
+	return slices.Concat(r.buf[r.next:], r.buf[:r.next])
+}
+
+// Replay returns an iterator over the values currently held, oldest first. The
+// values are copied when Replay is called, so recording more doesn't affect
+// it.
+func (r *Recorder[A]) Replay() iter.Seq[A] {
+	return Replay(r.Values())
+}
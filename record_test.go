@@ -0,0 +1,122 @@
+package it
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestRecord(t *testing.T) {
+	var recorded []int
+	var got []int
+	for v := range Record(slices.Values([]int{1, 2, 3, 4, 5}), &recorded) {
+		got = append(got, v)
+		if v == 3 {
+			break
+		}
+	}
+	if d := cmp.Diff(recorded, got); d != "" {
+		t.Errorf("recorded mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(slices.Collect(Replay(recorded)), []int{1, 2, 3}); d != "" {
+		t.Errorf("replay mismatch (-got, +want):\n%v", d)
+	}
+	// Ranging again carries on appending.
+	for range Record(slices.Values([]int{6, 7}), &recorded) {
+	}
+	if d := cmp.Diff(recorded, []int{1, 2, 3, 6, 7}); d != "" {
+		t.Errorf("recorded mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	for _, c := range []struct {
+		name          string
+		n             int
+		in            []int
+		want          []int
+		wantTruncated bool
+	}{{
+		name: "empty",
+		n:    3,
+	}, {
+		name: "partly-full",
+		n:    3,
+		in:   []int{1, 2},
+		want: []int{1, 2},
+	}, {
+		name: "exactly-full",
+		n:    3,
+		in:   []int{1, 2, 3},
+		want: []int{1, 2, 3},
+	}, {
+		name:          "one-over",
+		n:             3,
+		in:            []int{1, 2, 3, 4},
+		want:          []int{2, 3, 4},
+		wantTruncated: true,
+	}, {
+		name:          "wrapped-twice",
+		n:             3,
+		in:            []int{1, 2, 3, 4, 5, 6, 7, 8},
+		want:          []int{6, 7, 8},
+		wantTruncated: true,
+	}, {
+		name:          "capacity-one",
+		n:             1,
+		in:            []int{1, 2, 3},
+		want:          []int{3},
+		wantTruncated: true,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			r := NewRecorder[int](c.n)
+			got := slices.Collect(r.Record(slices.Values(c.in)))
+			if d := cmp.Diff(got, c.in, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("passed through mismatch (-got, +want):\n%v", d)
+			}
+			if d := cmp.Diff(r.Values(), c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("Values() mismatch (-got, +want):\n%v", d)
+			}
+			if d := cmp.Diff(slices.Collect(r.Replay()), c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("Replay() mismatch (-got, +want):\n%v", d)
+			}
+			if r.Truncated() != c.wantTruncated {
+				t.Errorf("Truncated() = %v, want %v", r.Truncated(), c.wantTruncated)
+			}
+			if r.Seen() != len(c.in) {
+				t.Errorf("Seen() = %d, want %d", r.Seen(), len(c.in))
+			}
+		})
+	}
+}
+
+func TestRecorderReplaySnapshot(t *testing.T) {
+	r := NewRecorder[int](2)
+	r.Add(1)
+	r.Add(2)
+	replay := r.Replay()
+	values := r.Values()
+	r.Add(3)
+	if d := cmp.Diff(slices.Collect(replay), []int{1, 2}); d != "" {
+		t.Errorf("replay changed after recording (-got, +want):\n%v", d)
+	}
+	values[0] = 100
+	if d := cmp.Diff(r.Values(), []int{2, 3}); d != "" {
+		t.Errorf("Values() mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestNewRecorderPanics(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewRecorder(%d) didn't panic", n)
+				}
+			}()
+			NewRecorder[int](n)
+		}()
+	}
+}
@@ -0,0 +1,116 @@
+// Package seqtest provides helpers for testing code that produces or consumes
+// iterators.
+package seqtest
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// AssertEqual collects got and want and reports an error if they yielded
+// different values.
+func AssertEqual[A comparable](t testing.TB, got, want iter.Seq[A]) {
+	t.Helper()
+	g, w := slices.Collect(got), slices.Collect(want)
+	if !slices.Equal(g, w) {
+		t.Errorf("got %v, want %v", g, w)
+	}
+}
+
+// Probe wraps an iterator and records how it is used, for checking that code
+// consuming it stops pulling values when it should. A Probe can be ranged
+// over more than once, the counts accumulate. It is safe for concurrent use.
+type Probe[A any] struct {
+	it iter.Seq[A]
+
+	mu      sync.Mutex
+	pulled  int
+	stopped bool
+	running int
+}
+
+// NewProbe returns a Probe wrapping it.
+func NewProbe[A any](it iter.Seq[A]) *Probe[A] {
+	return &Probe[A]{it: it}
+}
+
+// Seq returns the iterator to hand to the code under test.
+func (p *Probe[A]) Seq() iter.Seq[A] {
+	return func(yield func(A) bool) {
+		p.mu.Lock()
+		p.running++
+		p.mu.Unlock()
+		defer func() {
+			p.mu.Lock()
+			p.running--
+			p.mu.Unlock()
+		}()
+		for a := range p.it {
+			p.mu.Lock()
+			p.pulled++
+			p.mu.Unlock()
+			if !yield(a) {
+				p.mu.Lock()
+				p.stopped = true
+				p.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// Pulled returns the number of values the consumer has received.
+func (p *Probe[A]) Pulled() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pulled
+}
+
+// Stopped reports whether the consumer has ever stopped early, by returning
+// false from yield.
+func (p *Probe[A]) Stopped() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopped
+}
+
+// Running reports whether the consumer is still in the middle of ranging over
+// the iterator. This is the case if it is paused in a yield call, for example
+// by iter.Pull without stop having been called.
+func (p *Probe[A]) Running() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running > 0
+}
+
+// Brittle returns an iterator that yields the same values as it, but is
+// strict about how it is used. It panics if it is ranged over more than once,
+// or if it catches it yielding again after yield has returned false.
+func Brittle[A any](it iter.Seq[A]) iter.Seq[A] {
+	var (
+		mu     sync.Mutex
+		ranged bool
+	)
+	return func(yield func(A) bool) {
+		mu.Lock()
+		again := ranged
+		ranged = true
+		mu.Unlock()
+		if again {
+			panic("seqtest.Brittle: ranged over more than once")
+		}
+		done := false
+		it(func(a A) bool {
+			if done {
+				panic(fmt.Sprintf("seqtest.Brittle: yield called with %v after returning false", a))
+			}
+			if !yield(a) {
+				done = true
+			}
+			return !done
+		})
+	}
+}
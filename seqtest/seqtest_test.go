@@ -0,0 +1,122 @@
+package seqtest
+
+import (
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeT is enough of a testing.TB to see whether AssertEqual fails.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, format)
+}
+
+func TestAssertEqual(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		got, want []int
+		fail      bool
+	}{
+		{name: "empty"},
+		{name: "nil-and-empty", got: nil, want: []int{}},
+		{name: "equal", got: []int{1, 2, 3}, want: []int{1, 2, 3}},
+		{name: "different", got: []int{1, 2, 3}, want: []int{1, 2, 4}, fail: true},
+		{name: "shorter", got: []int{1, 2}, want: []int{1, 2, 3}, fail: true},
+		{name: "longer", got: []int{1, 2, 3}, want: []int{1, 2}, fail: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ft := &fakeT{}
+			AssertEqual(ft, slices.Values(c.got), slices.Values(c.want))
+			if failed := len(ft.errors) > 0; failed != c.fail {
+				t.Errorf("AssertEqual(%v, %v) failed: %v, want %v", c.got, c.want, failed, c.fail)
+			}
+		})
+	}
+}
+
+func TestProbe(t *testing.T) {
+	p := NewProbe(slices.Values([]int{1, 2, 3, 4}))
+	if p.Pulled() != 0 || p.Stopped() || p.Running() {
+		t.Fatalf("new probe: pulled %d, stopped %v, running %v", p.Pulled(), p.Stopped(), p.Running())
+	}
+	for v := range p.Seq() {
+		if !p.Running() {
+			t.Error("not running while ranging")
+		}
+		if v == 2 {
+			break
+		}
+	}
+	if p.Pulled() != 2 || !p.Stopped() || p.Running() {
+		t.Errorf("after break: pulled %d, stopped %v, running %v, want 2, true, false", p.Pulled(), p.Stopped(), p.Running())
+	}
+
+	p = NewProbe(slices.Values([]int{1, 2, 3, 4}))
+	next, stop := iter.Pull(p.Seq())
+	next()
+	if p.Pulled() != 1 || p.Stopped() || !p.Running() {
+		t.Errorf("after one pull: pulled %d, stopped %v, running %v, want 1, false, true", p.Pulled(), p.Stopped(), p.Running())
+	}
+	stop()
+	if p.Running() {
+		t.Error("still running after stop")
+	}
+
+	p = NewProbe(slices.Values([]int{1, 2, 3, 4}))
+	for range p.Seq() {
+	}
+	if p.Pulled() != 4 || p.Stopped() || p.Running() {
+		t.Errorf("after exhausting: pulled %d, stopped %v, running %v, want 4, false, false", p.Pulled(), p.Stopped(), p.Running())
+	}
+}
+
+// expectPanic calls f and returns the panic message, failing if there wasn't
+// one.
+func expectPanic(t *testing.T, f func()) (msg string) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected a panic")
+		}
+		msg, _ = r.(string)
+	}()
+	f()
+	return ""
+}
+
+func TestBrittle(t *testing.T) {
+	seq := Brittle(slices.Values([]int{1, 2, 3}))
+	if got := slices.Collect(seq); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("got %v, want [1 2 3]", got)
+	}
+	msg := expectPanic(t, func() {
+		for range seq {
+		}
+	})
+	if !strings.Contains(msg, "more than once") {
+		t.Errorf("unexpected panic message %q", msg)
+	}
+
+	// A badly behaved iterator that ignores yield returning false.
+	bad := func(yield func(int) bool) {
+		yield(1)
+		yield(2)
+	}
+	msg = expectPanic(t, func() {
+		next, stop := iter.Pull(Brittle(bad))
+		defer stop()
+		next()
+	})
+	if !strings.Contains(msg, "after returning false") {
+		t.Errorf("unexpected panic message %q", msg)
+	}
+}
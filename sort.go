@@ -0,0 +1,120 @@
+package it
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// Sorted collects the values of it into a new slice, sorted in ascending
+// order. It is the same as slices.Sorted.
+func Sorted[A cmp.Ordered](it iter.Seq[A]) []A {
+	return slices.Sorted(it)
+}
+
+// SortedSeq returns an iterator over the values of it in ascending order.
+// Nothing is read from it until the returned iterator is used, at which point
+// all of it is read and sorted before the first value is yielded. This happens
+// again every time the returned iterator is used.
+func SortedSeq[A cmp.Ordered](it iter.Seq[A]) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		for _, a := range Sorted(it) {
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// SortedFunc collects the values of it into a new slice, sorted in ascending
+// order as determined by cmp, which should follow the same rules as for
+// slices.SortFunc. The sort is not guaranteed to be stable: use
+// SortedStableFunc if the order of equal elements matters.
+func SortedFunc[A any](it iter.Seq[A], cmp func(A, A) int) []A {
+	return slices.SortedFunc(it, cmp)
+}
+
+// SortedStableFunc is like SortedFunc, but keeps equal elements in the order
+// they were yielded by it.
+func SortedStableFunc[A any](it iter.Seq[A], cmp func(A, A) int) []A {
+	return slices.SortedStableFunc(it, cmp)
+}
+
+// SortedBy collects the values of it into a new slice, stably sorted in
+// ascending order of the keys returned by key. key is called exactly once for
+// each value.
+func SortedBy[A any, K cmp.Ordered](it iter.Seq[A], key func(A) K) []A {
+	var keyed []Pair[K, A]
+	for a := range it {
+		keyed = append(keyed, NewPair(key(a), a))
+	}
+	slices.SortStableFunc(keyed, func(a, b Pair[K, A]) int { return cmp.Compare(a.A, b.A) })
+	sorted := make([]A, len(keyed))
+	for i, p := range keyed {
+		sorted[i] = p.B
+	}
+	return sorted
+}
+
+// Sorted2ByKey returns an iterator over the pairs of it, stably sorted in
+// ascending order of their keys, so that pairs with equal keys keep their
+// original order. Like SortedSeq, nothing is read from it until the returned
+// iterator is used, and then all of it is read before anything is yielded.
+// Sorted2ByKey(maps.All(m)) iterates over a map in a deterministic order.
+func Sorted2ByKey[K cmp.Ordered, V any](it iter.Seq2[K, V]) iter.Seq2[K, V] {
+	return Sorted2ByKeyFunc(it, cmp.Compare[K])
+}
+
+// Sorted2ByKeyFunc is like Sorted2ByKey, but compares keys using cmp.
+func Sorted2ByKeyFunc[K, V any](it iter.Seq2[K, V], cmp func(K, K) int) iter.Seq2[K, V] {
+	return sorted2(it, func(a, b Pair[K, V]) int { return cmp(a.A, b.A) })
+}
+
+// Sorted2ByValue is like Sorted2ByKey, but sorts by value.
+func Sorted2ByValue[K any, V cmp.Ordered](it iter.Seq2[K, V]) iter.Seq2[K, V] {
+	return Sorted2ByValueFunc(it, cmp.Compare[V])
+}
+
+// Sorted2ByValueFunc is like Sorted2ByValue, but compares values using cmp.
+func Sorted2ByValueFunc[K, V any](it iter.Seq2[K, V], cmp func(V, V) int) iter.Seq2[K, V] {
+	return sorted2(it, func(a, b Pair[K, V]) int { return cmp(a.B, b.B) })
+}
+
+func sorted2[K, V any](it iter.Seq2[K, V], cmp func(a, b Pair[K, V]) int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		pairs := Collect2(it)
+		slices.SortStableFunc(pairs, cmp)
+		for _, p := range pairs {
+			if !yield(p.A, p.B) {
+				return
+			}
+		}
+	}
+}
+
+// Reversed returns an iterator over the values of it in reverse order. This
+// means buffering all of it: like SortedSeq, nothing is read until the
+// returned iterator is used, and every time it is used all of it is read
+// before anything is yielded.
+func Reversed[A any](it iter.Seq[A]) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		values := slices.Collect(it)
+		for _, a := range slices.Backward(values) {
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// Reversed2 is like Reversed, but for an iter.Seq2.
+func Reversed2[A, B any](it iter.Seq2[A, B]) iter.Seq2[A, B] {
+	return func(yield func(A, B) bool) {
+		pairs := Collect2(it)
+		for _, p := range slices.Backward(pairs) {
+			if !yield(p.A, p.B) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package it
+
+import (
+	stdcmp "cmp"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestSorted(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   []int
+		want []int
+	}{
+		{name: "empty"},
+		{name: "one", in: []int{1}, want: []int{1}},
+		{name: "sorted", in: []int{1, 2, 3}, want: []int{1, 2, 3}},
+		{name: "reversed", in: []int{3, 2, 1}, want: []int{1, 2, 3}},
+		{name: "duplicates", in: []int{2, 3, 1, 2, 3, 1}, want: []int{1, 1, 2, 2, 3, 3}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			in := slices.Clone(c.in)
+			if d := cmp.Diff(Sorted(slices.Values(in)), c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("Sorted mismatch (-got, +want):\n%v", d)
+			}
+			if d := cmp.Diff(slices.Collect(SortedSeq(slices.Values(in))), c.want, cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("SortedSeq mismatch (-got, +want):\n%v", d)
+			}
+			if d := cmp.Diff(in, c.in); d != "" {
+				t.Errorf("input modified (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestSortedSeqDeferred(t *testing.T) {
+	src, pulled, _ := countingSeq(3, 1, 2)
+	seq := SortedSeq(src)
+	if *pulled != 0 {
+		t.Fatalf("pulled %d values before ranging", *pulled)
+	}
+	for v := range seq {
+		// Everything has to be read before the smallest is known.
+		if *pulled != 3 {
+			t.Errorf("pulled %d values before yielding the first, want 3", *pulled)
+		}
+		if v != 1 {
+			t.Errorf("first value %d, want 1", v)
+		}
+		break
+	}
+	// Each range reads the source again.
+	if got := slices.Collect(seq); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("second range got %v, want [1 2 3]", got)
+	}
+	if *pulled != 6 {
+		t.Errorf("pulled %d values after two ranges, want 6", *pulled)
+	}
+}
+
+type person struct {
+	Name string
+	Age  int
+}
+
+func byAge(a, b person) int { return stdcmp.Compare(a.Age, b.Age) }
+
+var people = []person{
+	{"Alice", 30},
+	{"Bob", 25},
+	{"Carol", 30},
+	{"Dave", 20},
+	{"Eve", 25},
+}
+
+func TestSortedFunc(t *testing.T) {
+	got := SortedFunc(slices.Values(people), byAge)
+	ages := slices.Collect(Map(slices.Values(got), func(p person) int { return p.Age }))
+	if want := []int{20, 25, 25, 30, 30}; !slices.Equal(ages, want) {
+		t.Errorf("got ages %v, want %v", ages, want)
+	}
+	if !slices.IsSortedFunc(got, byAge) {
+		t.Errorf("not sorted: %v", got)
+	}
+	if d := cmp.Diff(SortedFunc(slices.Values([]person(nil)), byAge), []person(nil), cmpopts.EquateEmpty()); d != "" {
+		t.Errorf("empty mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestSortedStable(t *testing.T) {
+	want := []person{{"Dave", 20}, {"Bob", 25}, {"Eve", 25}, {"Alice", 30}, {"Carol", 30}}
+	for _, c := range []struct {
+		name string
+		sort func(iter.Seq[person]) []person
+	}{{
+		name: "SortedStableFunc",
+		sort: func(it iter.Seq[person]) []person { return SortedStableFunc(it, byAge) },
+	}, {
+		name: "SortedBy",
+		sort: func(it iter.Seq[person]) []person {
+			return SortedBy(it, func(p person) int { return p.Age })
+		},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			if d := cmp.Diff(c.sort(slices.Values(people)), want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if d := cmp.Diff(c.sort(slices.Values([]person(nil))), []person(nil), cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("empty mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestSortedByCallsKeyOnce(t *testing.T) {
+	calls := 0
+	got := SortedBy(slices.Values(people), func(p person) string {
+		calls++
+		return p.Name
+	})
+	if calls != len(people) {
+		t.Errorf("key called %d times, want %d", calls, len(people))
+	}
+	if d := cmp.Diff(got, people); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestSorted2(t *testing.T) {
+	in := []Pair[string, int]{
+		{"b", 2}, {"a", 3}, {"c", 1}, {"a", 1}, {"b", 1}, {"a", 2},
+	}
+	reverse := func(a, b string) int { return stdcmp.Compare(b, a) }
+	for _, c := range []struct {
+		name string
+		sort func(iter.Seq2[string, int]) iter.Seq2[string, int]
+		want []Pair[string, int]
+	}{{
+		name: "by-key",
+		sort: Sorted2ByKey[string, int],
+		want: []Pair[string, int]{{"a", 3}, {"a", 1}, {"a", 2}, {"b", 2}, {"b", 1}, {"c", 1}},
+	}, {
+		name: "by-key-func",
+		sort: func(it iter.Seq2[string, int]) iter.Seq2[string, int] { return Sorted2ByKeyFunc(it, reverse) },
+		want: []Pair[string, int]{{"c", 1}, {"b", 2}, {"b", 1}, {"a", 3}, {"a", 1}, {"a", 2}},
+	}, {
+		name: "by-value",
+		sort: Sorted2ByValue[string, int],
+		want: []Pair[string, int]{{"c", 1}, {"a", 1}, {"b", 1}, {"b", 2}, {"a", 2}, {"a", 3}},
+	}, {
+		name: "by-value-func",
+		sort: func(it iter.Seq2[string, int]) iter.Seq2[string, int] {
+			return Sorted2ByValueFunc(it, func(a, b int) int { return b - a })
+		},
+		want: []Pair[string, int]{{"a", 3}, {"b", 2}, {"a", 2}, {"c", 1}, {"a", 1}, {"b", 1}},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			if d := cmp.Diff(Collect2(c.sort(Unpair(slices.Values(in)))), c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			if got := Collect2(c.sort(Unpair(slices.Values([]Pair[string, int](nil))))); len(got) != 0 {
+				t.Errorf("empty input got %v", got)
+			}
+		})
+	}
+}
+
+func TestSorted2ByKeyMap(t *testing.T) {
+	m := map[string]int{"z": 26, "a": 1, "m": 13, "b": 2}
+	want := []Pair[string, int]{{"a", 1}, {"b", 2}, {"m", 13}, {"z", 26}}
+	for range 10 {
+		if d := cmp.Diff(Collect2(Sorted2ByKey(maps.All(m))), want); d != "" {
+			t.Fatalf("mismatch (-got, +want):\n%v", d)
+		}
+	}
+}
+
+func TestReversed(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   []int
+		want []int
+	}{
+		{name: "empty"},
+		{name: "one", in: []int{1}, want: []int{1}},
+		{name: "several", in: []int{1, 2, 3, 2}, want: []int{2, 3, 2, 1}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			src, pulled, _ := countingSeq(c.in...)
+			seq := Reversed(src)
+			if *pulled != 0 {
+				t.Errorf("pulled %d values before ranging", *pulled)
+			}
+			// Ranging twice gives the same result.
+			for range 2 {
+				if d := cmp.Diff(slices.Collect(seq), c.want, cmpopts.EquateEmpty()); d != "" {
+					t.Errorf("mismatch (-got, +want):\n%v", d)
+				}
+			}
+
+			var in2 []Pair[int, string]
+			for i, v := range c.in {
+				in2 = append(in2, NewPair(v, strings.Repeat("x", i)))
+			}
+			want2 := slices.Clone(in2)
+			slices.Reverse(want2)
+			seq2 := Reversed2(Unpair(slices.Values(in2)))
+			for range 2 {
+				if d := cmp.Diff(Collect2(seq2), want2, cmpopts.EquateEmpty()); d != "" {
+					t.Errorf("Reversed2 mismatch (-got, +want):\n%v", d)
+				}
+			}
+		})
+	}
+}
+
+func TestReversedEarlyBreak(t *testing.T) {
+	var got []int
+	for v := range Reversed(slices.Values([]int{1, 2, 3, 4})) {
+		got = append(got, v)
+		if len(got) == 2 {
+			break
+		}
+	}
+	if d := cmp.Diff(got, []int{4, 3}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
@@ -0,0 +1,38 @@
+package it
+
+import (
+	"database/sql"
+	"errors"
+	"iter"
+)
+
+// Rows returns an iterator over the rows of rows, each turned into a T by scan,
+// which should call rows.Scan. Any error from scan or rows.Err is yielded as
+// the final element, joined with any error from closing rows. rows is always
+// closed when iteration finishes, including if the consumer stops early, in
+// which case any error from closing it is discarded. Like rows itself, the
+// returned iterator can only be used once. rows is closed even if scan or the
+// consumer panics.
+func Rows[T any](rows *sql.Rows, scan func(*sql.Rows) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		// Closing is idempotent, so this only does anything if scan or
+		// the consumer panics, or the consumer stops early.
+		defer rows.Close()
+		var zero T
+		for rows.Next() {
+			t, err := scan(rows)
+			if err != nil {
+				yield(zero, errors.Join(err, rows.Close()))
+				return
+			}
+			if !yield(t, nil) {
+				return
+			}
+		}
+		// Once Next returns false rows has already been closed, so this
+		// only matters if Next stopped because of an error.
+		if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+			yield(zero, err)
+		}
+	}
+}
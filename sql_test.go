@@ -0,0 +1,222 @@
+package it
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeDB is a database/sql driver that returns the same rows for every query.
+// Iterating the rows fails with nextErr after all of the rows have been
+// returned, if it is set, and closing them returns closeErr.
+type fakeDB struct {
+	rows     [][]driver.Value
+	nextErr  error
+	closeErr error
+	closed   int
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt(c), nil }
+func (fakeConn) Close() error                          { return nil }
+func (fakeConn) Begin() (driver.Tx, error)             { return nil, errors.ErrUnsupported }
+
+type fakeStmt struct{ db *fakeDB }
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.ErrUnsupported }
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{db: s.db}, nil
+}
+
+type fakeRows struct {
+	db *fakeDB
+	i  int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"name", "count"} }
+
+func (r *fakeRows) Close() error {
+	r.db.closed++
+	return r.db.closeErr
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i == len(r.db.rows) {
+		if r.db.nextErr != nil {
+			return r.db.nextErr
+		}
+		return io.EOF
+	}
+	copy(dest, r.db.rows[r.i])
+	r.i++
+	return nil
+}
+
+func (db *fakeDB) query(t *testing.T) *sql.Rows {
+	t.Helper()
+	sqlDB := sql.OpenDB(db)
+	t.Cleanup(func() { sqlDB.Close() })
+	rows, err := sqlDB.Query("SELECT name, count FROM records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func scanRow(rows *sql.Rows) (row, error) {
+	var r row
+	err := rows.Scan(&r.Name, &r.Count)
+	return r, err
+}
+
+func TestRows(t *testing.T) {
+	var (
+		errNext  = errors.New("next")
+		errClose = errors.New("close")
+		rows     = [][]driver.Value{{"a", int64(1)}, {"b", int64(2)}, {"c", int64(3)}}
+	)
+	for _, c := range []struct {
+		name    string
+		db      *fakeDB
+		limit   int
+		want    []row
+		wantErr error
+	}{{
+		name: "empty",
+		db:   &fakeDB{},
+	}, {
+		name: "all",
+		db:   &fakeDB{rows: rows},
+		want: []row{{"a", 1}, {"b", 2}, {"c", 3}},
+	}, {
+		name:  "early-break",
+		db:    &fakeDB{rows: rows, closeErr: errClose},
+		limit: 2,
+		want:  []row{{"a", 1}, {"b", 2}},
+	}, {
+		name:    "next-error",
+		db:      &fakeDB{rows: rows, nextErr: errNext},
+		want:    []row{{"a", 1}, {"b", 2}, {"c", 3}},
+		wantErr: errNext,
+	}, {
+		name:    "scan-error",
+		db:      &fakeDB{rows: [][]driver.Value{{"a", int64(1)}, {"b", "two"}}, closeErr: errClose},
+		want:    []row{{"a", 1}},
+		wantErr: errClose,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				got  []row
+				errs []error
+			)
+			for r, err := range Rows(c.db.query(t), scanRow) {
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				got = append(got, r)
+				if len(got) == c.limit {
+					break
+				}
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+			switch {
+			case len(errs) > 1:
+				t.Errorf("got %d errors, want at most 1: %v", len(errs), errs)
+			case c.wantErr == nil && len(errs) > 0:
+				t.Errorf("unexpected error: %v", errs[0])
+			case c.wantErr != nil && (len(errs) == 0 || !errors.Is(errs[0], c.wantErr)):
+				t.Errorf("got errors %v, want %v", errs, c.wantErr)
+			}
+			if c.db.closed != 1 {
+				t.Errorf("rows closed %d times, want 1", c.db.closed)
+			}
+		})
+	}
+}
+
+func TestRowsCollectErr(t *testing.T) {
+	db := &fakeDB{rows: [][]driver.Value{{"a", int64(1)}, {"b", int64(2)}}}
+	got, err := CollectErr(Rows(db.query(t), scanRow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, []row{{"a", 1}, {"b", 2}}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestRowsTwice(t *testing.T) {
+	db := &fakeDB{rows: [][]driver.Value{{"a", int64(1)}, {"b", int64(2)}}}
+	seq := Rows(db.query(t), scanRow)
+	if _, err := CollectErr(seq); err != nil {
+		t.Fatal(err)
+	}
+	got, err := CollectErr(seq)
+	if len(got) != 0 || err != nil {
+		t.Errorf("second range got %v, %v, want nothing", got, err)
+	}
+	if db.closed != 1 {
+		t.Errorf("rows closed %d times, want 1", db.closed)
+	}
+}
+
+func TestRowsPanic(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		scan func(*sql.Rows) (row, error)
+		body func(row)
+	}{{
+		name: "consumer",
+		scan: scanRow,
+		body: func(r row) {
+			if r.Name == "b" {
+				panic("consumer")
+			}
+		},
+	}, {
+		name: "scan",
+		scan: func(rows *sql.Rows) (row, error) {
+			r, err := scanRow(rows)
+			if r.Name == "b" {
+				panic("scan")
+			}
+			return r, err
+		},
+		body: func(row) {},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			db := &fakeDB{rows: [][]driver.Value{{"a", int64(1)}, {"b", int64(2)}, {"c", int64(3)}}}
+			rows := db.query(t)
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Error("expected panic")
+					}
+				}()
+				for r, err := range Rows(rows, c.scan) {
+					if err != nil {
+						t.Fatal(err)
+					}
+					c.body(r)
+				}
+			}()
+			if db.closed != 1 {
+				t.Errorf("rows closed %d times, want 1", db.closed)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package it
+
+import (
+	"fmt"
+	"iter"
+	"runtime"
+	"sync"
+)
+
+// Tee returns n iterators that each yield every value from it, like Python's
+// itertools.tee. The source is only ranged over once, lazily, as the returned
+// iterators need values from it. The returned iterators may be consumed at
+// different rates and from different goroutines.
+//
+// Values are buffered from the point the slowest consumer has reached up to
+// the point the fastest one has reached, so if one of them gets a long way
+// ahead of another the buffer can get large. Once an iterator stops, either
+// because the source is exhausted or because its consumer stops early, it no
+// longer holds on to any values. An iterator that is never ranged over holds
+// on to everything; to abandon one without using it, range over it and break
+// immediately. Each returned iterator should only be ranged over once, ranging
+// over one again yields nothing. When all of them have stopped the source is
+// stopped too. Tee panics if n is negative.
+func Tee[A any](it iter.Seq[A], n int) []iter.Seq[A] {
+	checkCount("it.Tee", n)
+	t := newTee(it, n)
+	seqs := make([]iter.Seq[A], n)
+	for i := range seqs {
+		seqs[i] = t.seq(i)
+	}
+	return seqs
+}
+
+// tee is the shared state behind the iterators returned by Tee.
+type tee[A any] struct {
+	mu     sync.Mutex
+	src    iter.Seq[A]
+	next   func() (A, bool)
+	stop   func()
+	done   bool
+	buf    []A
+	base   int // The position in the source of buf[0].
+	pos    []int
+	active []bool
+	live   int // The number of true values in active.
+}
+
+func newTee[A any](it iter.Seq[A], n int) *tee[A] {
+	t := &tee[A]{
+		src:    it,
+		pos:    make([]int, n),
+		active: make([]bool, n),
+		live:   n,
+	}
+	for i := range t.active {
+		t.active[i] = true
+	}
+	return t
+}
+
+func (t *tee[A]) seq(i int) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		defer t.finish(i)
+		for {
+			a, ok := t.get(i)
+			if !ok || !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// get returns the next value for consumer i, pulling from the source if
+// necessary.
+func (t *tee[A]) get(i int) (A, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var zero A
+	if !t.active[i] {
+		return zero, false
+	}
+	if t.pos[i] == t.base+len(t.buf) {
+		if t.done {
+			return zero, false
+		}
+		if t.next == nil {
+			t.next, t.stop = iter.Pull(t.src)
+		}
+		a, ok := t.next()
+		if !ok {
+			t.done = true
+			t.stop()
+			return zero, false
+		}
+		t.buf = append(t.buf, a)
+	}
+	a := t.buf[t.pos[i]-t.base]
+	t.pos[i]++
+	t.trim()
+	return a, true
+}
+
+// finish marks consumer i as no longer needing any values.
+func (t *tee[A]) finish(i int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.active[i] {
+		return
+	}
+	t.active[i] = false
+	t.live--
+	t.trim()
+	if t.live == 0 && t.stop != nil {
+		t.stop()
+	}
+}
+
+// trim discards any buffered values that all active consumers have already
+// seen.
+func (t *tee[A]) trim() {
+	lowest := t.base + len(t.buf)
+	for i, p := range t.pos {
+		if t.active[i] {
+			lowest = min(lowest, p)
+		}
+	}
+	k := lowest - t.base
+	if k == 0 {
+		return
+	}
+	// Clear the discarded values so they can be garbage collected even
+	// though they're still in the backing array.
+	clear(t.buf[:k])
+	t.buf = t.buf[k:]
+	t.base = lowest
+}
+
+// ShardN returns n iterators which between them yield the values of it, dealing
+// them out in turn so that the i'th value goes to the i%n'th iterator. it is
+// ranged over once, lazily, as the returned iterators need values, and they may
+// be consumed at different rates and from different goroutines.
+//
+// Each value is only held for the iterator it belongs to, and only until that
+// iterator yields it, so if the iterators are consumed in step very little is
+// buffered. If one gets a long way ahead of the others, the values it skipped
+// over are buffered for them. Once an iterator stops, the values for it are
+// discarded. Like Tee, an iterator that is never ranged over has everything
+// for it buffered, each iterator should only be ranged over once, and the
+// source is stopped when all of them have stopped. It panics if n is not
+// positive.
+func ShardN[A any](it iter.Seq[A], n int) []iter.Seq[A] {
+	if n <= 0 {
+		panic(fmt.Sprintf("it.ShardN: invalid number of shards %d", n))
+	}
+	s := newShards(it, n)
+	seqs := make([]iter.Seq[A], n)
+	for i := range seqs {
+		seqs[i] = s.seq(i)
+	}
+	return seqs
+}
+
+// shards is the shared state behind the iterators returned by ShardN.
+type shards[A any] struct {
+	mu     sync.Mutex
+	src    iter.Seq[A]
+	next   func() (A, bool)
+	stop   func()
+	done   bool
+	pulled int   // The number of values pulled from the source.
+	queues [][]A // The values pulled for each shard but not yet yielded.
+	active []bool
+	live   int // The number of true values in active.
+}
+
+func newShards[A any](it iter.Seq[A], n int) *shards[A] {
+	s := &shards[A]{
+		src:    it,
+		queues: make([][]A, n),
+		active: make([]bool, n),
+		live:   n,
+	}
+	for i := range s.active {
+		s.active[i] = true
+	}
+	return s
+}
+
+func (s *shards[A]) seq(i int) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		defer s.finish(i)
+		for {
+			a, ok := s.get(i)
+			if !ok || !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// get returns the next value for shard i, pulling from the source until there
+// is one if necessary. Values pulled along the way for other shards are queued
+// for them, unless they have stopped.
+func (s *shards[A]) get(i int) (A, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var zero A
+	if !s.active[i] {
+		return zero, false
+	}
+	for len(s.queues[i]) == 0 {
+		if s.done {
+			return zero, false
+		}
+		if s.next == nil {
+			s.next, s.stop = iter.Pull(s.src)
+		}
+		a, ok := s.next()
+		if !ok {
+			s.done = true
+			s.stop()
+			return zero, false
+		}
+		if k := s.pulled % len(s.queues); s.active[k] {
+			s.queues[k] = append(s.queues[k], a)
+		}
+		s.pulled++
+	}
+	q := s.queues[i]
+	a := q[0]
+	// Clear it so it can be garbage collected even though it's still in
+	// the backing array.
+	q[0] = zero
+	s.queues[i] = q[1:]
+	return a, true
+}
+
+// finish marks shard i as no longer needing any values.
+func (s *shards[A]) finish(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.active[i] {
+		return
+	}
+	s.active[i] = false
+	s.queues[i] = nil
+	s.live--
+	if s.live == 0 && s.stop != nil {
+		s.stop()
+	}
+}
+
+// buffered returns the number of values queued across all of the shards.
+func (s *shards[A]) buffered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, q := range s.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// Memoize returns an iterator that yields the same values as it, but can be
+// ranged over any number of times, even if it can't. Values are pulled from it
+// lazily, the first time any consumer needs them, and remembered for later
+// consumers, so memory grows with the number of distinct values pulled. The
+// returned iterator is safe to range over from multiple goroutines at once.
+//
+// If no consumer ever reaches the end of it, it is stopped once the returned
+// iterator is garbage collected.
+func Memoize[A any](it iter.Seq[A]) iter.Seq[A] {
+	m := &memo[A]{src: it}
+	return func(yield func(A) bool) {
+		for i := 0; ; i++ {
+			a, ok := m.get(i)
+			if !ok || !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+type memo[A any] struct {
+	mu     sync.Mutex
+	src    iter.Seq[A]
+	next   func() (A, bool)
+	done   bool
+	values []A
+}
+
+// get returns the ith value, pulling from the source if necessary.
+func (m *memo[A]) get(i int) (A, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i >= len(m.values) {
+		if m.done {
+			var zero A
+			return zero, false
+		}
+		if m.next == nil {
+			var stop func()
+			m.next, stop = iter.Pull(m.src)
+			runtime.AddCleanup(m, func(stop func()) { stop() }, stop)
+		}
+		a, ok := m.next()
+		if !ok {
+			m.done = true
+			continue
+		}
+		m.values = append(m.values, a)
+	}
+	return m.values[i], true
+}
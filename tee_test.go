@@ -0,0 +1,403 @@
+package it
+
+import (
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pfcm/it/seqtest"
+)
+
+func TestTee(t *testing.T) {
+	values := []int{1, 2, 3, 4, 5}
+	for n := range 5 {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			pulled := 0
+			src := func(yield func(int) bool) {
+				for _, v := range values {
+					pulled++
+					if !yield(v) {
+						return
+					}
+				}
+			}
+			seqs := Tee(src, n)
+			if len(seqs) != n {
+				t.Fatalf("got %d iterators, want %d", len(seqs), n)
+			}
+			// One after another.
+			for i, s := range seqs {
+				if d := cmp.Diff(slices.Collect(s), values); d != "" {
+					t.Fatalf("%d: mismatch (-got, +want):\n%v", i, d)
+				}
+			}
+			if n > 0 && pulled != len(values) {
+				t.Fatalf("source pulled %d times, want %d", pulled, len(values))
+			}
+		})
+	}
+}
+
+func TestTeeInterleaved(t *testing.T) {
+	values := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	for _, c := range []struct {
+		name string
+		// Which consumer to advance at each step, until they're all
+		// finished.
+		order []int
+	}{
+		{name: "round-robin", order: []int{0, 1, 2}},
+		{name: "one-ahead", order: []int{0, 0, 0, 1, 2, 0, 1, 1, 2}},
+		{name: "laggard", order: []int{0, 1, 0, 1, 0, 1, 0, 1, 2}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tt := newTee(slices.Values(values), 3)
+			var (
+				got       [3][]int
+				nexts     [3]func() (int, bool)
+				finished  [3]bool
+				maxBuffer int
+			)
+			for i := range nexts {
+				var stop func()
+				nexts[i], stop = iter.Pull(tt.seq(i))
+				defer stop()
+			}
+			for step := 0; !finished[0] || !finished[1] || !finished[2]; step++ {
+				i := c.order[step%len(c.order)]
+				if finished[i] {
+					// Let the others catch up.
+					i = slices.Index(finished[:], false)
+				}
+				v, ok := nexts[i]()
+				if !ok {
+					finished[i] = true
+					continue
+				}
+				got[i] = append(got[i], v)
+				maxBuffer = max(maxBuffer, len(tt.buf))
+			}
+			for i := range got {
+				if d := cmp.Diff(got[i], values); d != "" {
+					t.Fatalf("%d: mismatch (-got, +want):\n%v", i, d)
+				}
+			}
+			if len(tt.buf) != 0 {
+				t.Fatalf("%d values still buffered", len(tt.buf))
+			}
+			t.Logf("max buffer: %d", maxBuffer)
+		})
+	}
+}
+
+func TestTeeAbandoned(t *testing.T) {
+	stopped := false
+	src := func(yield func(int) bool) {
+		defer func() { stopped = true }()
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	tt := newTee(src, 2)
+	a, b := tt.seq(0), tt.seq(1)
+
+	// a gets well ahead, so everything is buffered for b.
+	if d := cmp.Diff(slices.Collect(Limit(a, 10)), slices.Collect(Limit(naturals(), 10))); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if len(tt.buf) != 10 {
+		t.Fatalf("%d values buffered, want 10", len(tt.buf))
+	}
+	// a has stopped, so only b's backlog is needed.
+	if d := cmp.Diff(slices.Collect(Limit(b, 4)), []int{0, 1, 2, 3}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if len(tt.buf) != 0 {
+		t.Fatalf("%d values still buffered after both stopped", len(tt.buf))
+	}
+	if !stopped {
+		t.Fatal("source not stopped after all consumers stopped")
+	}
+	// Ranging again yields nothing.
+	if got := slices.Collect(a); got != nil {
+		t.Fatalf("expected nothing, got %v", got)
+	}
+}
+
+func TestTeeAbandonedReclaimsBacklog(t *testing.T) {
+	tt := newTee(naturals(), 2)
+	a, b := tt.seq(0), tt.seq(1)
+	next, stop := iter.Pull(a)
+	defer stop()
+	for range 10 {
+		next()
+	}
+	if len(tt.buf) != 10 {
+		t.Fatalf("%d values buffered, want 10", len(tt.buf))
+	}
+	// Abandon b without really using it.
+	for range b {
+		break
+	}
+	if len(tt.buf) != 0 {
+		t.Fatalf("%d values still buffered after abandoning the slow consumer", len(tt.buf))
+	}
+}
+
+func TestTeeConcurrent(t *testing.T) {
+	want := slices.Collect(Limit(naturals(), 1000))
+	seqs := Tee(slices.Values(want), 4)
+	var wg sync.WaitGroup
+	got := make([][]int, len(seqs))
+	for i, s := range seqs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i] = slices.Collect(s)
+		}()
+	}
+	wg.Wait()
+	for i := range got {
+		if d := cmp.Diff(got[i], want); d != "" {
+			t.Fatalf("%d: mismatch (-got, +want):\n%v", i, d)
+		}
+	}
+}
+
+func TestShardN(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		len  int
+		n    int
+		want [][]int
+	}{
+		{name: "empty", len: 0, n: 2, want: [][]int{nil, nil}},
+		{name: "fewer", len: 2, n: 3, want: [][]int{{0}, {1}, nil}},
+		{name: "equal", len: 3, n: 3, want: [][]int{{0}, {1}, {2}}},
+		{name: "not-divisible", len: 8, n: 3, want: [][]int{{0, 3, 6}, {1, 4, 7}, {2, 5}}},
+		{name: "one", len: 3, n: 1, want: [][]int{{0, 1, 2}}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			shards := ShardN(Limit(naturals(), c.len), c.n)
+			// One at a time, in reverse order to make sure the
+			// later shards buffer for the earlier ones.
+			got := make([][]int, len(shards))
+			for i, shard := range slices.Backward(shards) {
+				got[i] = slices.Collect(shard)
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestShardNLazy(t *testing.T) {
+	p := seqtest.NewProbe(naturals())
+	shards := ShardN(p.Seq(), 3)
+	if p.Pulled() != 0 {
+		t.Fatalf("pulled %d values before ranging", p.Pulled())
+	}
+	next, stop := iter.Pull(shards[1])
+	if v, _ := next(); v != 1 {
+		t.Errorf("first value of shard 1 = %d, want 1", v)
+	}
+	if v, _ := next(); v != 4 {
+		t.Errorf("second value of shard 1 = %d, want 4", v)
+	}
+	if p.Pulled() != 5 {
+		t.Errorf("pulled %d values for two from shard 1, want 5", p.Pulled())
+	}
+	stop()
+	for _, shard := range []iter.Seq[int]{shards[0], shards[2]} {
+		for range shard {
+			break
+		}
+	}
+	if p.Running() {
+		t.Error("source still running after every shard stopped")
+	}
+}
+
+func TestShardNConcurrent(t *testing.T) {
+	const n, total = 4, 1000
+	shards := ShardN(Limit(naturals(), total), n)
+	got := make([][]int, n)
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i] = slices.Collect(shard)
+		}()
+	}
+	wg.Wait()
+	for i, values := range got {
+		for j, v := range values {
+			if v != i+j*n {
+				t.Fatalf("shard %d value %d = %d, want %d", i, j, v, i+j*n)
+			}
+		}
+		if len(values) != total/n {
+			t.Errorf("shard %d got %d values, want %d", i, len(values), total/n)
+		}
+	}
+}
+
+func TestShardNLockstep(t *testing.T) {
+	const n = 4
+	p := seqtest.NewProbe(naturals())
+	s := newShards(p.Seq(), n)
+	var (
+		nexts = make([]func() (int, bool), n)
+		stops = make([]func(), n)
+	)
+	for i := range n {
+		nexts[i], stops[i] = iter.Pull(s.seq(i))
+	}
+	for round := range 10000 {
+		for i, next := range nexts {
+			if v, _ := next(); v != round*n+i {
+				t.Fatalf("shard %d round %d got %d, want %d", i, round, v, round*n+i)
+			}
+			// Nothing is buffered, since each shard's value is
+			// pulled just as it is needed.
+			if b := s.buffered(); b != 0 {
+				t.Fatalf("%d values buffered after round %d shard %d", b, round, i)
+			}
+		}
+	}
+	if got, want := p.Pulled(), 10000*n; got != want {
+		t.Errorf("pulled %d values, want %d", got, want)
+	}
+	for _, stop := range stops {
+		stop()
+	}
+	if p.Running() {
+		t.Error("source still running after every shard stopped")
+	}
+}
+
+func TestShardNBuffering(t *testing.T) {
+	s := newShards(naturals(), 3)
+	next, stop := iter.Pull(s.seq(0))
+	defer stop()
+	for range 10 {
+		next()
+	}
+	// Shard 0 is 10 values ahead, so 9 values each are queued for the
+	// others, but nothing for shard 0 itself.
+	if b := s.buffered(); b != 18 {
+		t.Fatalf("%d values buffered, want 18", b)
+	}
+	if d := cmp.Diff(slices.Collect(Limit(s.seq(1), 3)), []int{1, 4, 7}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	// Shard 1 has stopped, so its queue is dropped, and it doesn't get any
+	// more as shard 0 carries on.
+	if b := s.buffered(); b != 9 {
+		t.Fatalf("%d values buffered after shard 1 stopped, want 9", b)
+	}
+	for range 10 {
+		next()
+	}
+	if b := s.buffered(); b != 19 {
+		t.Fatalf("%d values buffered, want 19", b)
+	}
+}
+
+func TestSplitShardPanics(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		f    func()
+	}{
+		{name: "SplitN", f: func() { SplitN(naturals(), 0) }},
+		{name: "ShardN", f: func() { ShardN(naturals(), -1) }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			defer func() {
+				if msg, _ := recover().(string); !strings.HasPrefix(msg, "it."+c.name+":") {
+					t.Errorf("got panic %q, want one from it.%s", msg, c.name)
+				}
+			}()
+			c.f()
+			t.Error("no panic")
+		})
+	}
+}
+
+func TestMemoize(t *testing.T) {
+	values := []int{1, 2, 3, 4, 5}
+	pulled := 0
+	// A single-use source.
+	next, stop := iter.Pull(slices.Values(values))
+	defer stop()
+	src := func(yield func(int) bool) {
+		for {
+			v, ok := next()
+			if !ok {
+				return
+			}
+			pulled++
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	m := Memoize(src)
+
+	// Only pulls as far as the consumer needs.
+	if d := cmp.Diff(slices.Collect(Limit(m, 2)), []int{1, 2}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if pulled != 2 {
+		t.Fatalf("pulled %d values, want 2", pulled)
+	}
+	// Later consumers start from the beginning, and keep going.
+	for range 3 {
+		if d := cmp.Diff(slices.Collect(m), values); d != "" {
+			t.Fatalf("mismatch (-got, +want):\n%v", d)
+		}
+	}
+	if pulled != len(values) {
+		t.Fatalf("pulled %d values, want %d", pulled, len(values))
+	}
+}
+
+func TestMemoizeConcurrent(t *testing.T) {
+	want := slices.Collect(Limit(naturals(), 1000))
+	pulled := 0
+	src := func(yield func(int) bool) {
+		for _, v := range want {
+			pulled++
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	m := Memoize(src)
+	var wg sync.WaitGroup
+	got := make([][]int, 8)
+	for i := range got {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i] = slices.Collect(m)
+		}()
+	}
+	wg.Wait()
+	for i := range got {
+		if d := cmp.Diff(got[i], want); d != "" {
+			t.Fatalf("%d: mismatch (-got, +want):\n%v", i, d)
+		}
+	}
+	if pulled != len(want) {
+		t.Fatalf("pulled %d values, want %d", pulled, len(want))
+	}
+}
@@ -0,0 +1,326 @@
+package it
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Clock is the source of time for the iterators in this package that need one,
+// so that it can be replaced in tests. A nil Clock means the real one.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel that receives the current time once d has
+	// elapsed, like time.After.
+	After(d time.Duration) <-chan time.Time
+	// NewTicker returns a Ticker that ticks every d, like time.NewTicker.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is a source of regular ticks, like a *time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+	// Stop turns off the ticker.
+	Stop()
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+type systemTicker struct{ *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.Ticker.C }
+
+func clockOrDefault(c Clock) Clock {
+	if c != nil {
+		return c
+	}
+	return systemClock{}
+}
+
+// Throttle returns an iterator that yields the same values as it, in the same
+// order, but at most n of them in any window of length per, sleeping as
+// necessary. After a quiet period, up to n values may be yielded immediately.
+// It panics if n or per are not positive.
+func Throttle[A any](it iter.Seq[A], n int, per time.Duration) iter.Seq[A] {
+	return ThrottleCtx(context.Background(), it, n, per, nil)
+}
+
+// ThrottleCtx is like Throttle, but stops if ctx is cancelled, including while
+// waiting, and uses the provided clock, or the real one if it is nil.
+func ThrottleCtx[A any](ctx context.Context, it iter.Seq[A], n int, per time.Duration, clock Clock) iter.Seq[A] {
+	if n <= 0 || per <= 0 {
+		panic(fmt.Sprintf("it.Throttle: invalid rate %d per %v", n, per))
+	}
+	clock = clockOrDefault(clock)
+	return func(yield func(A) bool) {
+		// The times of the last n values yielded, oldest at next once
+		// it has filled up.
+		var (
+			last = make([]time.Time, 0, n)
+			next = 0
+		)
+		for a := range it {
+			if ctx.Err() != nil {
+				return
+			}
+			if len(last) == n {
+				if wait := last[next].Add(per).Sub(clock.Now()); wait > 0 {
+					select {
+					case <-ctx.Done():
+						return
+					case <-clock.After(wait):
+					}
+				}
+				last[next] = clock.Now()
+				next = (next + 1) % n
+			} else {
+				last = append(last, clock.Now())
+			}
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// BatchTimeout returns an iterator that yields batches of values received from
+// ch. A batch is yielded as soon as it has n values, or once d has passed since
+// its first value was received, whichever happens first. Any partial batch is
+// yielded when ch is closed or ctx is cancelled, after which the iterator
+// finishes. Like Batch, the yielded slice is reused between batches. It panics
+// if n or d are not positive.
+func BatchTimeout[A any](ctx context.Context, ch <-chan A, n int, d time.Duration) iter.Seq[[]A] {
+	return BatchTimeoutClock(ctx, ch, n, d, nil)
+}
+
+// BatchTimeoutClock is BatchTimeout using the provided clock, or the real one
+// if it is nil.
+func BatchTimeoutClock[A any](ctx context.Context, ch <-chan A, n int, d time.Duration, clock Clock) iter.Seq[[]A] {
+	if n <= 0 || d <= 0 {
+		panic(fmt.Sprintf("it.BatchTimeout: invalid batch size %d or timeout %v", n, d))
+	}
+	clock = clockOrDefault(clock)
+	return func(yield func([]A) bool) {
+		batch := make([]A, 0, n)
+		// A fresh timer channel for each batch, so there's never a
+		// stale expiry to worry about. Nil while the batch is empty.
+		var timeout <-chan time.Time
+		flush := func() bool {
+			timeout = nil
+			if len(batch) == 0 {
+				return true
+			}
+			ok := yield(batch)
+			batch = batch[:0]
+			return ok
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				flush()
+				return
+			case <-timeout:
+				if !flush() {
+					return
+				}
+			case a, ok := <-ch:
+				if !ok {
+					flush()
+					return
+				}
+				if len(batch) == 0 {
+					timeout = clock.After(d)
+				}
+				batch = append(batch, a)
+				if len(batch) == n && !flush() {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Tick returns an iterator that yields the time every d, until ctx is
+// cancelled. Like a time.Ticker, it drops ticks to make up for a slow
+// consumer. The underlying ticker is stopped when iteration finishes. It
+// panics if d is not positive.
+func Tick(ctx context.Context, d time.Duration) iter.Seq[time.Time] {
+	return TickClock(ctx, d, nil)
+}
+
+// TickClock is Tick using the provided clock, or the real one if it is nil.
+func TickClock(ctx context.Context, d time.Duration, clock Clock) iter.Seq[time.Time] {
+	if d <= 0 {
+		panic(fmt.Sprintf("it.Tick: non-positive interval %v", d))
+	}
+	clock = clockOrDefault(clock)
+	return func(yield func(time.Time) bool) {
+		ticker := clock.NewTicker(d)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case t := <-ticker.C():
+				if !yield(t) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Backoff returns an infinite iterator over delays for retrying an operation,
+// starting at base and multiplying by factor each time, up to maxDelay. If
+// jitter is positive, each delay d is then moved by a random amount drawn using
+// r, to somewhere in [d·(1-jitter), d·(1+jitter)], so a jittered delay can be
+// up to jitter·maxDelay more than maxDelay, though never more than the largest
+// time.Duration. If r is nil a private randomly seeded source is used. Use Take
+// to limit the number of attempts:
+//
+//	for d := range Take(Backoff(time.Millisecond, time.Second, 2, 0.1, nil), 5) {
+//		if err := try(); err == nil {
+//			break
+//		}
+//		time.Sleep(d)
+//	}
+//
+// It panics if base is not positive, maxDelay is less than base, factor is less
+// than one, or jitter is outside [0, 1].
+func Backoff(base, maxDelay time.Duration, factor, jitter float64, r *rand.Rand) iter.Seq[time.Duration] {
+	if base <= 0 || maxDelay < base {
+		panic(fmt.Sprintf("it.Backoff: invalid delays %v up to %v", base, maxDelay))
+	}
+	if !(factor >= 1) {
+		panic(fmt.Sprintf("it.Backoff: factor %v less than 1", factor))
+	}
+	if !(jitter >= 0 && jitter <= 1) {
+		panic(fmt.Sprintf("it.Backoff: jitter %v out of range [0, 1]", jitter))
+	}
+	return func(yield func(time.Duration) bool) {
+		r := r
+		if jitter > 0 {
+			r = randOrDefault(r)
+		}
+		// Kept as a float so that it can't overflow before reaching
+		// the cap.
+		d := float64(base)
+		for {
+			delay := d
+			if jitter > 0 {
+				delay *= 1 + jitter*(2*r.Float64()-1)
+			}
+			// Jitter can push a delay near the cap past the
+			// largest Duration, which doesn't convert. The float64
+			// nearest to the largest Duration is also too big.
+			next := time.Duration(math.MaxInt64)
+			if delay < math.MaxInt64 {
+				next = time.Duration(delay)
+			}
+			if !yield(next) {
+				return
+			}
+			d = min(d*factor, float64(maxDelay))
+		}
+	}
+}
+
+// ProgressEvery is like Progress, but calls report with the number of values
+// yielded so far and the time since iteration started whenever at least d has
+// passed since the last report, and once more when iteration finishes. The
+// time is only checked between values, so a slow source or consumer delays the
+// report rather than having it interrupt them. It panics if d is not positive.
+func ProgressEvery[A any](it iter.Seq[A], d time.Duration, report func(done int, elapsed time.Duration)) iter.Seq[A] {
+	return ProgressEveryClock(it, d, report, nil)
+}
+
+// ProgressEveryClock is ProgressEvery using the provided clock, or the real one
+// if it is nil.
+func ProgressEveryClock[A any](it iter.Seq[A], d time.Duration, report func(done int, elapsed time.Duration), clock Clock) iter.Seq[A] {
+	if d <= 0 {
+		panic(fmt.Sprintf("it.ProgressEvery: non-positive interval %v", d))
+	}
+	clock = clockOrDefault(clock)
+	return func(yield func(A) bool) {
+		var (
+			start = clock.Now()
+			last  = start
+			done  = 0
+		)
+		for a := range it {
+			done++
+			if !yield(a) {
+				break
+			}
+			if now := clock.Now(); now.Sub(last) >= d {
+				report(done, now.Sub(start))
+				last = now
+			}
+		}
+		report(done, clock.Now().Sub(start))
+	}
+}
+
+// Hooks are callbacks for Instrument. Any of them may be nil.
+type Hooks struct {
+	// OnStart is called when iteration starts, before anything is pulled
+	// from the source.
+	OnStart func()
+	// OnYield is called each time the consumer finishes with a value and
+	// asks for the next, with the number of values yielded so far and how
+	// long the consumer spent on the value.
+	OnYield func(n int, wait time.Duration)
+	// OnDone is called when iteration finishes, with the total number of
+	// values yielded and the time since iteration started. abandoned is
+	// true if the consumer stopped early.
+	OnDone func(n int, total time.Duration, abandoned bool)
+}
+
+// Instrument returns an iterator that yields the same values as it, calling
+// the hooks as it goes. The time spent in the consumer compared to the total
+// time shows whether a stage in a pipeline is waiting on the stages before it
+// or those after it. Times are measured using the monotonic clock, so they
+// aren't affected by changes to the wall clock.
+func Instrument[A any](it iter.Seq[A], hooks Hooks) iter.Seq[A] {
+	return InstrumentClock(it, hooks, nil)
+}
+
+// InstrumentClock is Instrument using the provided clock, or the real one if
+// it is nil.
+func InstrumentClock[A any](it iter.Seq[A], hooks Hooks, clock Clock) iter.Seq[A] {
+	clock = clockOrDefault(clock)
+	return func(yield func(A) bool) {
+		if hooks.OnStart != nil {
+			hooks.OnStart()
+		}
+		var (
+			start     = clock.Now()
+			n         = 0
+			abandoned = false
+		)
+		for a := range it {
+			n++
+			before := clock.Now()
+			ok := yield(a)
+			if hooks.OnYield != nil {
+				hooks.OnYield(n, clock.Now().Sub(before))
+			}
+			if !ok {
+				abandoned = true
+				break
+			}
+		}
+		if hooks.OnDone != nil {
+			hooks.OnDone(n, clock.Now().Sub(start), abandoned)
+		}
+	}
+}
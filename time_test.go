@@ -0,0 +1,631 @@
+package it
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeClock is a Clock that only moves when told to. Channels returned by
+// After are sent to when the clock is advanced past their deadline. If auto
+// is set, calling After immediately advances the clock to the deadline, so
+// anything waiting on it doesn't actually wait.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	auto    bool
+	waiters []fakeWaiter
+	tickers []*fakeTicker
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock(auto bool) *fakeClock {
+	return &fakeClock{
+		now:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		auto: auto,
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeWaiter{deadline: c.now.Add(d), ch: ch})
+	auto := c.auto
+	c.mu.Unlock()
+	if auto {
+		c.Advance(d)
+	}
+	return ch
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{clock: c, d: d, next: c.now.Add(d), ch: make(chan time.Time, 1)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, firing any waiters whose deadline has
+// passed and ticking any tickers that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		for !t.next.After(c.now) {
+			select {
+			case t.ch <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.d)
+		}
+	}
+	c.waiters = slices.DeleteFunc(c.waiters, func(w fakeWaiter) bool {
+		if w.deadline.After(c.now) {
+			return false
+		}
+		w.ch <- c.now
+		return true
+	})
+}
+
+// Waiters returns the number of channels from After that haven't fired yet.
+func (c *fakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// Tickers returns the number of tickers that haven't been stopped.
+func (c *fakeClock) Tickers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.tickers)
+}
+
+type fakeTicker struct {
+	clock *fakeClock
+	d     time.Duration
+	next  time.Time
+	ch    chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.clock.tickers = slices.DeleteFunc(t.clock.tickers, func(u *fakeTicker) bool { return u == t })
+}
+
+func TestThrottle(t *testing.T) {
+	const (
+		n   = 3
+		per = time.Second
+	)
+	clock := newFakeClock(true)
+	start := clock.Now()
+
+	var offsets []time.Duration
+	for range ThrottleCtx(t.Context(), Limit(naturals(), 10), n, per, clock) {
+		offsets = append(offsets, clock.Now().Sub(start))
+	}
+	// Bursts of n, at most once per window.
+	want := []time.Duration{
+		0, 0, 0,
+		per, per, per,
+		2 * per, 2 * per, 2 * per,
+		3 * per,
+	}
+	if d := cmp.Diff(offsets, want); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestThrottleBurstAfterIdle(t *testing.T) {
+	const (
+		n   = 4
+		per = time.Second
+	)
+	clock := newFakeClock(true)
+	start := clock.Now()
+
+	var (
+		offsets []time.Duration
+		values  []int
+	)
+	for v := range ThrottleCtx(t.Context(), Limit(naturals(), 12), n, per, clock) {
+		values = append(values, v)
+		offsets = append(offsets, clock.Now().Sub(start))
+		if v == 5 {
+			// Go quiet for long enough to refill the bucket.
+			clock.Advance(10 * time.Second)
+		}
+	}
+	if d := cmp.Diff(values, slices.Collect(Limit(naturals(), 12))); d != "" {
+		t.Fatalf("values mismatch (-got, +want):\n%v", d)
+	}
+	// Never more than n in any window.
+	for i := n; i < len(offsets); i++ {
+		if offsets[i]-offsets[i-n] < per {
+			t.Fatalf("values %d to %d yielded within %v", i-n, i, offsets[i]-offsets[i-n])
+		}
+	}
+	// The four after the pause come out immediately.
+	for i := 7; i < 10; i++ {
+		if offsets[i] != offsets[6] {
+			t.Fatalf("value %d delayed after idle period: %v", i, offsets)
+		}
+	}
+}
+
+func TestThrottleCancel(t *testing.T) {
+	clock := newFakeClock(false)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan []int)
+	go func() {
+		var got []int
+		for v := range ThrottleCtx(ctx, naturals(), 2, time.Second, clock) {
+			got = append(got, v)
+		}
+		done <- got
+	}()
+	// Wait for it to start waiting, after the initial burst.
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if d := cmp.Diff(<-done, []int{0, 1}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestThrottleRealClock(t *testing.T) {
+	values := []int{1, 2, 3, 4}
+	start := time.Now()
+	got := slices.Collect(Throttle(slices.Values(values), 2, 20*time.Millisecond))
+	if d := cmp.Diff(got, values); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("finished too quickly: %v", elapsed)
+	}
+}
+
+// collectBatches ranges over batches in a new goroutine, sending a copy of each
+// batch on the returned channel, which is closed when it finishes.
+func collectBatches[A any](batches iter.Seq[[]A]) <-chan []A {
+	out := make(chan []A, 100)
+	go func() {
+		defer close(out)
+		for b := range batches {
+			out <- slices.Clone(b)
+		}
+	}()
+	return out
+}
+
+// waitFor polls until f returns true.
+func waitFor(t *testing.T, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBatchTimeoutSize(t *testing.T) {
+	defer checkGoroutines(t)()
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := range 7 {
+			ch <- i
+		}
+	}()
+	var got [][]int
+	for b := range collectBatches(BatchTimeoutClock(t.Context(), ch, 3, time.Second, newFakeClock(false))) {
+		got = append(got, b)
+	}
+	if d := cmp.Diff(got, [][]int{{0, 1, 2}, {3, 4, 5}, {6}}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestBatchTimeoutTime(t *testing.T) {
+	defer checkGoroutines(t)()
+	clock := newFakeClock(false)
+	ch := make(chan int)
+	batches := collectBatches(BatchTimeoutClock(t.Context(), ch, 3, time.Second, clock))
+
+	// The timer starts with the first value of the batch.
+	ch <- 1
+	waitFor(t, func() bool { return clock.Waiters() == 1 })
+	clock.Advance(500 * time.Millisecond)
+	ch <- 2
+	clock.Advance(499 * time.Millisecond)
+	select {
+	case b := <-batches:
+		t.Fatalf("batch %v yielded too early", b)
+	default:
+	}
+	clock.Advance(time.Millisecond)
+	if d := cmp.Diff(<-batches, []int{1, 2}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+
+	// A full batch is yielded straight away, and the old timer doesn't
+	// affect the next batch.
+	ch <- 3
+	ch <- 4
+	ch <- 5
+	if d := cmp.Diff(<-batches, []int{3, 4, 5}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	ch <- 6
+	waitFor(t, func() bool { return clock.Waiters() == 2 })
+	clock.Advance(time.Second)
+	if d := cmp.Diff(<-batches, []int{6}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+
+	// Nothing happens while the batch is empty.
+	clock.Advance(time.Hour)
+	ch <- 7
+	close(ch)
+	if d := cmp.Diff(<-batches, []int{7}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if b, ok := <-batches; ok {
+		t.Fatalf("unexpected batch %v", b)
+	}
+}
+
+func TestBatchTimeoutCancel(t *testing.T) {
+	defer checkGoroutines(t)()
+	ctx, cancel := context.WithCancel(t.Context())
+	ch := make(chan int)
+	batches := collectBatches(BatchTimeoutClock(ctx, ch, 3, time.Second, newFakeClock(false)))
+	ch <- 1
+	ch <- 2
+	cancel()
+	if d := cmp.Diff(<-batches, []int{1, 2}); d != "" {
+		t.Fatalf("mismatch (-got, +want):\n%v", d)
+	}
+	if b, ok := <-batches; ok {
+		t.Fatalf("unexpected batch %v", b)
+	}
+}
+
+func TestBatchTimeoutRealClock(t *testing.T) {
+	ch := make(chan int, 1)
+	ch <- 1
+	for b := range BatchTimeout(t.Context(), ch, 10, 10*time.Millisecond) {
+		if d := cmp.Diff(b, []int{1}); d != "" {
+			t.Fatalf("mismatch (-got, +want):\n%v", d)
+		}
+		break
+	}
+}
+
+func TestTick(t *testing.T) {
+	defer checkGoroutines(t)()
+	const d = time.Second
+	clock := newFakeClock(false)
+	start := clock.Now()
+
+	ticks := make(chan time.Time)
+	go func() {
+		defer close(ticks)
+		for tick := range Limit(TickClock(t.Context(), d, clock), 3) {
+			ticks <- tick
+		}
+	}()
+	waitFor(t, func() bool { return clock.Tickers() == 1 })
+	var got []time.Duration
+	for range 3 {
+		clock.Advance(d)
+		got = append(got, (<-ticks).Sub(start))
+	}
+	if _, ok := <-ticks; ok {
+		t.Error("got more than 3 ticks")
+	}
+	if d := cmp.Diff(got, []time.Duration{d, 2 * d, 3 * d}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+	if n := clock.Tickers(); n != 0 {
+		t.Errorf("%d tickers not stopped after early break", n)
+	}
+}
+
+func TestTickCancel(t *testing.T) {
+	defer checkGoroutines(t)()
+	clock := newFakeClock(false)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range TickClock(ctx, time.Second, clock) {
+			t.Error("unexpected tick")
+		}
+	}()
+	waitFor(t, func() bool { return clock.Tickers() == 1 })
+	cancel()
+	<-done
+	if n := clock.Tickers(); n != 0 {
+		t.Errorf("%d tickers not stopped after cancel", n)
+	}
+}
+
+func TestTickReal(t *testing.T) {
+	defer checkGoroutines(t)()
+	var prev time.Time
+	for tick := range Limit(Tick(t.Context(), time.Millisecond), 3) {
+		if !tick.After(prev) {
+			t.Errorf("tick %v not after previous %v", tick, prev)
+		}
+		prev = tick
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, c := range []struct {
+		name           string
+		base, maxDelay time.Duration
+		factor         float64
+		want           []time.Duration
+	}{{
+		name:     "doubling",
+		base:     time.Millisecond,
+		maxDelay: 10 * time.Millisecond,
+		factor:   2,
+		want: []time.Duration{
+			time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 8 * time.Millisecond,
+			10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond,
+		},
+	}, {
+		name:     "fractional-factor",
+		base:     100 * time.Millisecond,
+		maxDelay: time.Second,
+		factor:   1.5,
+		want: []time.Duration{
+			100 * time.Millisecond, 150 * time.Millisecond, 225 * time.Millisecond, 337500 * time.Microsecond,
+			506250 * time.Microsecond, 759375 * time.Microsecond, time.Second, time.Second,
+		},
+	}, {
+		name:     "constant",
+		base:     time.Second,
+		maxDelay: time.Second,
+		factor:   3,
+		want:     []time.Duration{time.Second, time.Second, time.Second},
+	}, {
+		name:     "factor-one",
+		base:     time.Second,
+		maxDelay: time.Minute,
+		factor:   1,
+		want:     []time.Duration{time.Second, time.Second, time.Second},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got := slices.Collect(Take(Backoff(c.base, c.maxDelay, c.factor, 0, nil), len(c.want)))
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestBackoffHuge(t *testing.T) {
+	// Lots of doubling mustn't overflow past the cap.
+	for d := range Take(Backoff(time.Nanosecond, time.Hour, 2, 0, nil), 200) {
+		if d <= 0 || d > time.Hour {
+			t.Fatalf("got delay %v", d)
+		}
+	}
+	// Nor must jitter push a delay near the largest Duration past it.
+	const maxDuration = time.Duration(math.MaxInt64)
+	sawMax := false
+	for d := range Take(Backoff(time.Second, maxDuration, 2, 0.5, rand.New(rand.NewPCG(1, 2))), 200) {
+		if d <= 0 {
+			t.Fatalf("got delay %v", d)
+		}
+		sawMax = sawMax || d == maxDuration
+	}
+	if !sawMax {
+		t.Error("jittered delays never reached the largest Duration")
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	const jitter = 0.25
+	seq := Backoff(time.Millisecond, 100*time.Millisecond, 2, jitter, rand.New(rand.NewPCG(1, 2)))
+	unjittered := slices.Collect(Take(Backoff(time.Millisecond, 100*time.Millisecond, 2, 0, nil), 20))
+	got := slices.Collect(Take(seq, 20))
+	for i, d := range got {
+		lo := time.Duration(float64(unjittered[i]) * (1 - jitter))
+		hi := time.Duration(float64(unjittered[i]) * (1 + jitter))
+		if d < lo || d > hi {
+			t.Errorf("delay %d = %v, want in [%v, %v]", i, d, lo, hi)
+		}
+	}
+	if slices.Equal(got, unjittered) {
+		t.Error("jitter had no effect")
+	}
+	// The same seed gives the same delays.
+	again := slices.Collect(Take(Backoff(time.Millisecond, 100*time.Millisecond, 2, jitter, rand.New(rand.NewPCG(1, 2))), 20))
+	if d := cmp.Diff(again, got); d != "" {
+		t.Errorf("seeded delays differ (-got, +want):\n%v", d)
+	}
+}
+
+func TestBackoffPanics(t *testing.T) {
+	for _, c := range []struct {
+		base, maxDelay time.Duration
+		factor, jitter float64
+	}{
+		{0, time.Second, 2, 0},
+		{time.Second, time.Millisecond, 2, 0},
+		{time.Millisecond, time.Second, 0.5, 0},
+		{time.Millisecond, time.Second, 2, -0.1},
+		{time.Millisecond, time.Second, 2, 1.5},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Backoff(%v, %v, %v, %v) didn't panic", c.base, c.maxDelay, c.factor, c.jitter)
+				}
+			}()
+			Backoff(c.base, c.maxDelay, c.factor, c.jitter, nil)
+		}()
+	}
+}
+
+func TestProgressEvery(t *testing.T) {
+	clock := newFakeClock(false)
+	// Each value takes 300ms to produce.
+	src := func(yield func(int) bool) {
+		for i := 1; i <= 7; i++ {
+			clock.Advance(300 * time.Millisecond)
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	var events []string
+	report := func(done int, elapsed time.Duration) {
+		events = append(events, fmt.Sprintf("report %d after %v", done, elapsed))
+	}
+	for v := range ProgressEveryClock(src, time.Second, report, clock) {
+		events = append(events, fmt.Sprint("got ", v))
+		if v == 5 {
+			// A slow consumer.
+			clock.Advance(time.Second)
+		}
+	}
+	want := []string{
+		"got 1", "got 2", "got 3", "got 4",
+		"report 4 after 1.2s",
+		"got 5",
+		"report 5 after 2.5s",
+		"got 6", "got 7",
+		"report 7 after 3.1s",
+	}
+	if d := cmp.Diff(events, want); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestProgressEveryEarlyBreak(t *testing.T) {
+	clock := newFakeClock(false)
+	var events []string
+	report := func(done int, elapsed time.Duration) {
+		events = append(events, fmt.Sprintf("report %d after %v", done, elapsed))
+	}
+	for v := range ProgressEveryClock(naturals(), time.Second, report, clock) {
+		clock.Advance(400 * time.Millisecond)
+		if v == 3 {
+			break
+		}
+	}
+	want := []string{"report 3 after 1.2s", "report 4 after 1.6s"}
+	if d := cmp.Diff(events, want); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		// How long the consumer takes over each value.
+		work []time.Duration
+		stop int
+		want []string
+	}{{
+		name: "empty",
+		want: []string{"start", "done 0 after 0s"},
+	}, {
+		name: "all",
+		work: []time.Duration{time.Second, 0, 2 * time.Second},
+		want: []string{
+			"start",
+			"yield 1 waited 1s",
+			"yield 2 waited 0s",
+			"yield 3 waited 2s",
+			"done 3 after 3.3s",
+		},
+	}, {
+		name: "abandoned",
+		work: []time.Duration{time.Second, 0, 2 * time.Second},
+		stop: 2,
+		want: []string{
+			"start",
+			"yield 1 waited 1s",
+			"yield 2 waited 0s",
+			"done 2 after 1.2s abandoned",
+		},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			clock := newFakeClock(false)
+			// Each value takes 100ms to produce.
+			src := func(yield func(int) bool) {
+				for i := range c.work {
+					clock.Advance(100 * time.Millisecond)
+					if !yield(i + 1) {
+						return
+					}
+				}
+			}
+			var events []string
+			hooks := Hooks{
+				OnStart: func() { events = append(events, "start") },
+				OnYield: func(n int, wait time.Duration) {
+					events = append(events, fmt.Sprintf("yield %d waited %v", n, wait))
+				},
+				OnDone: func(n int, total time.Duration, abandoned bool) {
+					e := fmt.Sprintf("done %d after %v", n, total)
+					if abandoned {
+						e += " abandoned"
+					}
+					events = append(events, e)
+				},
+			}
+			for v := range InstrumentClock(src, hooks, clock) {
+				clock.Advance(c.work[v-1])
+				if v == c.stop {
+					break
+				}
+			}
+			if d := cmp.Diff(events, c.want); d != "" {
+				t.Errorf("mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestInstrumentNilHooks(t *testing.T) {
+	got := slices.Collect(Instrument(slices.Values([]int{1, 2, 3}), Hooks{}))
+	if d := cmp.Diff(got, []int{1, 2, 3}); d != "" {
+		t.Errorf("mismatch (-got, +want):\n%v", d)
+	}
+}